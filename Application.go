@@ -3,6 +3,7 @@ package main
 import (
 	//Global Imports
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"sort"
@@ -27,6 +28,9 @@ type Program struct {
 var programs = map[int]Program{
 	0: {Name: "Quit", MainExecution: func() { fmt.Println("-------- Ending Simulation -------") }},
 	1: {Name: "Connect4", MainExecution: func() { c4.PlayConnect4() }},
+	2: {Name: "Connect4 engine match", MainExecution: func() { c4.WatchConnect4() }},
+	3: {Name: "Connect4 engine tournament", MainExecution: func() { c4.PlayTournament() }},
+	4: {Name: "Connect4 engine SPRT", MainExecution: func() { c4.RunSPRT() }},
 }
 
 // ----------------------------------------------------------------
@@ -48,6 +52,23 @@ Requirements for the programs is that they must have a means to start and stop o
 of the application.
 */
 func main() {
+	//Programs can take their settings from the command line
+	c4.RegisterFlags(flag.CommandLine)
+	protocol := flag.Bool("protocol", false, "speak the Connect 4 engine protocol on stdin and stdout instead of showing the menu")
+	protocolID := c4.DefaultProtocolID
+	flag.StringVar(&protocolID.Name, "protocol-name", protocolID.Name, "the engine's name in the Connect 4 engine protocol")
+	flag.StringVar(&protocolID.Author, "protocol-author", protocolID.Author, "the engine's author in the Connect 4 engine protocol")
+	flag.Parse()
+
+	//Other programs driving the engine only want the protocol on stdout
+	if *protocol {
+		if err := c4.ServeProtocol(os.Stdin, os.Stdout, protocolID); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("------------- Initializing Go Project Selection -------------")
 	choice := promptSelction()
 
@@ -2,58 +2,538 @@
 package connect4
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// How many plies the CPU looks ahead past its own move at most
+const cpuSearchDepth uint = 8
+
+// How long the CPU may think about a move before it plays the best one it has found
+const cpuThinkTime = 2 * time.Second
+
+// How many stones need to be on the board before the perfect play opponent starts solving
+const solverMinStones uint = 14
+
+// The file the game in progress is saved to, in the directory the program is run from
+const saveFile = "connect4-save.json"
+
+// The archive finished games are added to as game records, in the directory the program is run from
+const recordFile = "connect4-games.txt"
+
+// OpponentChoice is an entry in the menu of computer opponents offered when a game starts
+type OpponentChoice struct {
+	Name      string
+	NewEngine func() Engine
+
+	// WithDepth returns the engine searching depth plies past its own move instead of its usual depth,
+	// nil for engines that don't search to a depth
+	WithDepth    func(depth uint) Engine
+	DefaultDepth uint // the depth NewEngine searches to
+}
+
+// The computer opponents to choose from, new engines only need an entry here
+var opponents = map[int]OpponentChoice{
+	1: {Name: "Random", NewEngine: func() Engine { return NewRandomEngine(time.Now().UnixNano()) }},
+	2: {Name: "Greedy (one move ahead)", NewEngine: func() Engine { return GreedyEngine{} }},
+	3: {Name: "Minimax (depth 4)", NewEngine: func() Engine { return NewMiniMaxEngine(4) },
+		WithDepth: func(depth uint) Engine { return NewMiniMaxEngine(depth) }, DefaultDepth: 4},
+	4: {Name: "Iterative deepening", NewEngine: func() Engine { return NewTimedEngine(cpuThinkTime, cpuSearchDepth) },
+		WithDepth: func(depth uint) Engine { return NewTimedEngine(cpuThinkTime, depth) }, DefaultDepth: cpuSearchDepth},
+	5: {Name: "Perfect play", NewEngine: func() Engine {
+		return NewSolverEngine(solverMinStones, cpuThinkTime, NewTimedEngine(cpuThinkTime, cpuSearchDepth))
+	}, WithDepth: func(depth uint) Engine {
+		return NewSolverEngine(solverMinStones, cpuThinkTime, NewTimedEngine(cpuThinkTime, depth))
+	}, DefaultDepth: cpuSearchDepth},
+	6: {Name: "Monte Carlo tree search", NewEngine: func() Engine { return NewMCTSEngine(0, cpuThinkTime, time.Now().UnixNano()) }},
+}
+
 // Main function to play the Connect 4 game from list of programs
-// This function is designed to let you play against a single CPU that will predict the best moves possible against you
-// this is done by a MiniMax algorithm that will look ahead a certain depth to determine the best move
+// This function is designed to let you play against a CPU that will predict the best moves possible against you,
+// against another person on the same terminal, or to watch two CPUs play each other
+// the CPU opponents are picked from a menu, most of them look ahead a certain depth to determine the best move
 // This is a refactor of previous work done in the past for an assignment to create a simple Connect4 Game
 func PlayConnect4() {
 	fmt.Println("------------- Initializing Connect 4 -------------")
-	displayDirections()
+	//The last game's setup, with anything given on the command line, is where a new one starts from
+	setup := loadSetup()
+	given := applyFlags(&setup)
+	glyphs := pieceGlyphs(setup)
+	displayDirections(glyphs)
 
-	//Assign the Players
-	p1Inc := incrementer()
-	p2Inc := incrementer()
+	//Carry on with the saved game or set up a new one
+	session, settings, ok := resumeGame()
+	if !ok {
+		session, settings, setup = newGame(setup, given)
+		glyphs = pieceGlyphs(setup)
+	}
+	hasHuman := false
+	for _, p := range session.Players() {
+		hasHuman = hasHuman || p.IsHuman
+		if p.IsHuman {
+			fmt.Printf("%s (%s) is played by a human\n", p.Name, glyphs.Glyph(p.Piece))
+		} else {
+			fmt.Printf("%s (%s) is played by the computer: %s\n", p.Name, glyphs.Glyph(p.Piece), p.Engine.Name())
+		}
+	}
 
-	var player1 Player = Player{Name: "Player", TurnCount: p1Inc, Piece: PlayerIcon, IsHuman: true}
-	var player2 Player = Player{Name: "Computer", TurnCount: p2Inc, Piece: CpuIcon, IsHuman: false}
+	//Every move is saved so the game can be resumed if the program is closed,
+	//and recorded with the computer's evals for the archive
+	recorder := NewGameRecorder(session)
+	session.AddListener(ConsoleListener{Glyphs: glyphs})
+	session.AddListener(recorder)
+	session.AddListener(SaveListener{Path: saveFile, Session: session, Settings: settings, OnError: func(err error) {
+		fmt.Println("The game could not be saved:", err)
+	}})
 
-	//Define the initial game board setting the current player's turn to Black
-	var gameBoard C4Board = NewBoard()
+	fmt.Println("\nCurrent Board:")
+	fmt.Printf("%s", session.Board().Draw(glyphs))
 
-	//Main Loop for the game until there is a win or a draw
-	for !gameBoard.IsGameOver() {
-		fmt.Println("%s\nCurrent Board:%s\n")
-		fmt.Printf("%s", gameBoard.String())
+	//Main Loop for the game until there is a win or a draw, which can be taken back to play on
+	for {
+		for !session.IsOver() {
+			p := session.ToMove()
 
-		// player1 move - for now we pass player and expect player input handled by MakeMove
-		gameBoard = gameBoard.MakePlayerMove(player1)
-		player1.TurnCount()
+			var move Move
+			var result SearchResult
+			var scored bool
+			if p.IsHuman {
+				var played bool
+				var err error
+				if move, played, err = promptPlayerMove(session, p); err != nil {
+					fmt.Println("\nThere is nothing more to read, the game stops here.")
+					return
+				} else if !played {
+					continue // moves were taken back or replayed
+				}
+			} else {
+				result, scored = engineMove(session.Board(), p)
+				//Engines that report their search can show what they saw after each move
+				if _, canReport := p.Engine.(SearchEngine); canReport && settings.ShowAnalysis {
+					fmt.Printf("%s analysis: %s\n", p.Name, result)
+				}
+				move = result.Move
+			}
 
-		if gameBoard.IsGameOver() {
-			break
+			if err := session.Play(move); err != nil {
+				fmt.Println("Move refused:", err)
+			} else if scored {
+				recorder.Annotate(result)
+			}
 		}
 
-		gameBoard = gameBoard.MakeMove(player2, ConcurrentFindBestMove(gameBoard, player2, 3)) //Concurrent without inputted Depth
-		player2.TurnCount()
-		if gameBoard.IsGameOver() {
-			break
+		if !hasHuman || !promptYesNo("Undo your last move and play on?") || !undoTurn(session) {
+			// a finished game can't be resumed, it goes into the archive instead
+			if err := RemoveSave(saveFile); err != nil {
+				fmt.Println("The saved game could not be removed:", err)
+			}
+			if err := AppendRecord(recordFile, recorder.Record()); err != nil {
+				fmt.Println("The game could not be recorded:", err)
+			} else {
+				fmt.Printf("The game was added to %s\n", recordFile)
+			}
+			return
+		}
+	}
+}
+
+// engineMove asks p's engine for a move, returning false if the engine can't say how good the move is.
+// Only a SearchEngine fills in more of the result than the move and its score.
+func engineMove(board C4Board, p Player) (SearchResult, bool) {
+	switch e := p.Engine.(type) {
+	case SearchEngine:
+		return e.Search(board, p), true
+	case ScoringEngine:
+		move, score := e.BestMoveScore(board, p)
+		return SearchResult{Move: move, Score: score}, true
+	}
+	return SearchResult{Move: p.Engine.BestMove(board, p)}, false
+}
+
+// seatPieces are the pieces of the two seats, the first seat moves first
+var seatPieces = [2]Piece{PlayerIcon, CpuIcon}
+
+// newPlayer returns the player in seat 0 or 1, a human if opponent is 0 and otherwise the computer
+// playing the opponent with that key in the opponents menu, searching depth plies if it isn't 0
+func newPlayer(seat int, name string, opponent int, depth uint) Player {
+	p := Player{Name: name, TurnCount: incrementer(), Piece: seatPieces[seat], IsHuman: opponent == 0}
+	if !p.IsHuman {
+		p.Engine = opponents[opponent].NewEngine()
+		if depth > 0 && opponents[opponent].WithDepth != nil {
+			p.Engine = opponents[opponent].WithDepth(depth)
+		}
+	}
+	return p
+}
+
+// newGame asks for the choices of the setup that weren't given, whether to show the analysis and
+// for the board and starts a game on it, returning the setup chosen. The choices are remembered for the next game.
+func newGame(setup GameSetup, given map[string]bool) (*Session, GameSettings, GameSetup) {
+	setup = promptSetup(setup, given)
+	saveSetup(setup)
+
+	players, settings := setup.players()
+	canReport := false
+	for _, p := range players {
+		_, searches := p.Engine.(SearchEngine)
+		canReport = canReport || searches
+	}
+	settings.ShowAnalysis = canReport && promptYesNo("Show the computer's analysis after each move?")
+
+	return NewSessionOn(promptBoard(), players[0], players[1]), settings, setup
+}
+
+// pieceGlyphs returns the glyphs the setup draws the pieces with, or if they can't be drawn says so
+// and returns the plain ones
+func pieceGlyphs(setup GameSetup) Glyphs {
+	glyphs, err := setup.glyphs()
+	if err != nil {
+		fmt.Printf("The pieces can't be drawn as %q (%v), they are drawn as %s instead.\n", setup.Glyphs, err, DefaultGlyphs)
+		return Glyphs{}
+	}
+	return glyphs
+}
+
+// resumeGame offers to carry on the game in the save file, returning false if there is none
+// or the player would rather start a new one. A save file that can't be resumed is reported and left alone.
+func resumeGame() (*Session, GameSettings, bool) {
+	saved, err := LoadGame(saveFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, GameSettings{}, false
+	}
+	if err != nil {
+		fmt.Println("The saved game could not be read:", err)
+		return nil, GameSettings{}, false
+	}
+
+	session, err := resumeSaved(saved)
+	if err != nil {
+		fmt.Println("The saved game can't be resumed:", err)
+		return nil, GameSettings{}, false
+	}
+	players := session.Players()
+
+	fmt.Printf("There is a saved game of %s against %s from %s, %d moves in on a %dx%d board.\n",
+		players[0].Name, players[1].Name, saved.SavedAt.Format("Jan 2 15:04"), len(saved.Moves), saved.Board.Cols, saved.Board.Rows)
+	if !promptYesNo("Resume it?") {
+		return nil, GameSettings{}, false
+	}
+
+	return session, saved.Settings, true
+}
+
+// resumeSaved gives the players of a saved game their engines from the opponents menu back and resumes it.
+// Returns ErrInvalidSave if a computer player's engine is not on the menu.
+func resumeSaved(saved SavedGame) (*Session, error) {
+	var players [2]Player
+	for seat, p := range saved.Players {
+		opponent := saved.Settings.SeatOpponent(seat)
+		if p.IsHuman {
+			opponent = 0
+		} else if _, ok := opponents[opponent]; !ok {
+			return nil, fmt.Errorf("%w: computer opponent %d is not on the menu", ErrInvalidSave, opponent)
 		}
+		players[seat] = newPlayer(seat, p.Name, opponent, saved.Settings.Depths[seat])
 	}
+	return saved.Resume(players[0], players[1])
 }
 
-func displayDirections() {
+// ConsoleListener prints a session's events to the terminal, drawing the boards with Glyphs
+type ConsoleListener struct {
+	Glyphs Glyphs
+}
+
+func (l ConsoleListener) HandleEvent(e Event) {
+	switch e := e.(type) {
+	case MoveMadeEvent:
+		if e.Col.IsPop() {
+			fmt.Printf("\n%s popped column %d\n", e.Player.Name, e.Col.Col())
+		} else {
+			fmt.Printf("\n%s played column %d\n", e.Player.Name, e.Col)
+		}
+		if !e.Board.IsGameOver() {
+			fmt.Println("Current Board:")
+			fmt.Printf("%s", e.Board.Draw(l.Glyphs))
+		}
+	case UndoEvent:
+		fmt.Printf("\nTook back %d move(s)\n", len(e.Moves))
+		fmt.Println("Current Board:")
+		fmt.Printf("%s", e.Board.Draw(l.Glyphs))
+	case RedoEvent:
+		fmt.Printf("\nPlayed %d move(s) again\n", len(e.Moves))
+		if !e.Board.IsGameOver() {
+			fmt.Println("Current Board:")
+			fmt.Printf("%s", e.Board.Draw(l.Glyphs))
+		}
+	case WinnerEvent:
+		fmt.Println("\nTHAT'S THE GAME FOLKS!")
+		fmt.Println("Final Board Position:")
+		fmt.Print(e.Board.DrawHighlighted(l.Glyphs, e.Line))
+		fmt.Printf("THE WINNER IS: %s (%s)\n", e.Winner.Name, l.Glyphs.Glyph(e.Winner.Piece))
+	case DrawEvent:
+		fmt.Println("\nTHAT'S THE GAME FOLKS!")
+		fmt.Println("Final Board Position:")
+		fmt.Print(e.Board.Draw(l.Glyphs))
+		fmt.Println("IT'S A DRAW!")
+	}
+}
+
+// promptPlayerMove asks a human player for a column until they enter one that is a legal move.
+// In PopOut a p in front of the column pops it instead.
+// The player can also undo or redo moves, then it returns false and the caller should ask whose turn it is again.
+// Returns io.EOF once there is nothing left to read.
+func promptPlayerMove(session *Session, p Player) (Move, bool, error) {
+	board := session.Board()
+	if board.IsPopOut() {
+		fmt.Printf("%s, enter a Column you would like to insert in(0-%d), or p and a column to pop (p0-p%d): \n",
+			p.Name, board.numCols-1, board.numCols-1)
+	} else {
+		fmt.Printf("%s, enter a Column you would like to insert in(0-%d): \n", p.Name, board.numCols-1)
+	}
+	for {
+		answer, ok := readLine()
+		if !ok {
+			return 0, false, io.EOF
+		}
+		switch strings.ToLower(answer) {
+		case "undo":
+			if undoTurn(session) {
+				return 0, false, nil
+			}
+			fmt.Println("There are no moves to undo, please enter a column: ")
+			continue
+		case "redo":
+			if redoTurn(session) {
+				return 0, false, nil
+			}
+			fmt.Println("There are no moves to redo, please enter a column: ")
+			continue
+		}
+
+		col, err := parseMoveInput(answer)
+		if err != nil {
+			fmt.Println("That was not a column number, please try again: ")
+			continue
+		}
+		if _, err := board.TryMove(p, col); err != nil {
+			fmt.Printf("That was not a legal move (%v), please try again: \n", errors.Unwrap(err))
+			continue
+		}
+		return col, true, nil
+	}
+}
+
+// undoTurn takes back moves until it is a human player's turn again, so the computer's reply goes too.
+// Returns false if there was nothing to take back.
+func undoTurn(session *Session) bool {
+	history := session.History()
+	if len(history) == 0 {
+		return false
+	}
+	// whoever made a move is to play again once it is taken back
+	plies := 1
+	for plies < len(history) && !history[len(history)-plies].Player.IsHuman {
+		plies++
+	}
+	return session.Undo(plies) == nil
+}
+
+// redoTurn plays taken back moves again until it is a human player's turn.
+// Returns false if there was nothing to play again.
+func redoTurn(session *Session) bool {
+	moves := session.RedoMoves()
+	if len(moves) == 0 {
+		return false
+	}
+	plies := 1
+	for plies < len(moves) && !moves[plies].Player.IsHuman {
+		plies++
+	}
+	return session.Redo(plies) == nil
+}
+
+// parseMoveInput reads a move typed in by a player, a column number or p and a column number for a pop
+func parseMoveInput(answer string) (Move, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	pop := strings.HasPrefix(answer, "p")
+	col, err := strconv.ParseUint(strings.TrimPrefix(answer, "p"), 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	if pop {
+		return PopMove(uint(col)), nil
+	}
+	return Move(col), nil
+}
+
+func displayDirections(glyphs Glyphs) {
 	fmt.Println("--------------------------------------------------")
 	fmt.Println("---------------- Game Directions -----------------")
 	fmt.Println("--------------------------------------------------")
-	fmt.Println("You are playing as Black (X) and the Computer is Red (O)")
-	fmt.Println("To make a move, enter the column number (0-6) where you want to drop your piece.")
+	fmt.Println("Either player can be a human or the computer, two people can play each other on one terminal.")
+	fmt.Printf("The first player plays %s and the second %s, the glyphs can be changed before a new game.\n",
+		glyphs.Glyph(PlayerIcon), glyphs.Glyph(CpuIcon))
+	fmt.Println("Your choices are remembered for the next game, run with -help to see how to give them on the command line.")
+	fmt.Println("To make a move, enter the column number (counting from 0) where you want to drop your piece.")
+	fmt.Println("Playing PopOut, enter p and a column number to pop your piece off the bottom of that column.")
+	fmt.Println("Enter undo to take back your last move and redo to play it again.")
+	fmt.Println("The game is saved after every move, you can resume it the next time you play.")
+	fmt.Printf("Finished games are recorded in %s.\n", recordFile)
 	fmt.Println("--------------------------------------------------")
 
 }
 
+// promptOpponent shows the numbered list of opponents and reads a choice until a listed number is entered,
+// returning its key. Returns false once there is nothing left to read.
+func promptOpponent() (int, bool) {
+	keys := make([]int, 0, len(opponents))
+	for k := range opponents {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	for {
+		fmt.Println("Please select your opponent:")
+		for _, k := range keys {
+			fmt.Printf("  %2d) %s\n", k, opponents[k].Name)
+		}
+		fmt.Print("Enter choice: ")
+
+		answer, ok := readLine()
+		if !ok {
+			return 0, false
+		}
+		var choice int
+		if _, err := fmt.Sscanf(answer, "%d", &choice); err != nil {
+			fmt.Println("Invalid input. Please enter a number.")
+			continue
+		}
+		if _, ok := opponents[choice]; ok {
+			return choice, true
+		}
+		fmt.Println("Selection not available. Choose one of the listed numbers.")
+	}
+}
+
+// promptBoard asks for the board size as columns x rows, how many in a row win and whether to play PopOut,
+// empty answers are the standard board and four in a row, and so is the end of the input
+func promptBoard() C4Board {
+	cols, rows := uint(NumCols), uint(NumRows)
+	for {
+		fmt.Printf("Board size as columns x rows, from %dx%d to %dx%d (Enter for %dx%d): ",
+			MinCols, MinRows, MaxCols, MaxRows, NumCols, NumRows)
+
+		answer, _ := readLine()
+		answer = strings.ToLower(answer)
+		if answer == "" {
+			break
+		}
+		if _, err := fmt.Sscanf(answer, "%dx%d", &cols, &rows); err != nil {
+			fmt.Println("Please enter the size like 7x6.")
+			continue
+		}
+		if _, err := NewBoardSize(cols, rows); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		break
+	}
+
+	for {
+		fmt.Printf("Pieces in a row to win, from %d to %d (Enter for %d): ", MinWinLength, max(cols, rows), WinLength)
+
+		answer, _ := readLine()
+		winLength := uint(WinLength)
+		if answer != "" {
+			if _, err := fmt.Sscanf(answer, "%d", &winLength); err != nil {
+				fmt.Println("Please enter a number.")
+				continue
+			}
+		}
+
+		board, err := NewConnectNBoard(cols, rows, winLength)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if promptYesNo("Play PopOut (pop your own pieces off the bottom of a column)?") {
+			board, _ = NewPopOutBoard(cols, rows, winLength)
+		}
+		return board
+	}
+}
+
+// ------------------------------------------------------
+// Reading the terminal
+// ------------------------------------------------------
+// Everything typed in is read by one goroutine that owns stdin, started the first time an answer is
+// needed. The prompts and the command readers running alongside a game all take their lines from it,
+// so a command reader that has stopped can't swallow the answer to the next prompt.
+
+// stdin holds the lines typed in, nil until the reader is started
+var stdin struct {
+	sync.Mutex
+	lines <-chan string
+}
+
+// inputLines returns the lines typed in, the channel is closed at the end of the input
+func inputLines() <-chan string {
+	stdin.Lock()
+	defer stdin.Unlock()
+	if stdin.lines == nil {
+		stdin.lines = readLines(os.Stdin)
+	}
+	return stdin.lines
+}
+
+// readLines sends every line of r on the returned channel from a goroutine of its own and closes it at the end of r
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// readLine reads a whole line typed in, spaces included, without the spaces around it.
+// Returns false once there is nothing left to read.
+func readLine() (string, bool) {
+	line, ok := <-inputLines()
+	return strings.TrimSpace(line), ok
+}
+
+// promptYesNo asks a yes or no question until it gets a y or an n, the end of the input is a no
+func promptYesNo(question string) bool {
+	for {
+		fmt.Printf("%s (y/n): ", question)
+
+		answer, ok := readLine()
+		if !ok {
+			return false
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Println("Please answer y or n.")
+	}
+}
+
 // Generic Incrementer closure
 func incrementer() func() int {
 	count := 0
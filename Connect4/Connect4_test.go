@@ -0,0 +1,74 @@
+package connect4
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// withStdin makes input what is typed in for the rest of the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	withStdinReader(t, strings.NewReader(input))
+}
+
+// withStdinReader makes what r reads what is typed in for the rest of the test
+func withStdinReader(t *testing.T, r io.Reader) {
+	t.Helper()
+	stdin.Lock()
+	stdin.lines = readLines(r)
+	stdin.Unlock()
+	t.Cleanup(func() {
+		stdin.Lock()
+		stdin.lines = nil
+		stdin.Unlock()
+	})
+}
+
+func TestReadLine(t *testing.T) {
+	withStdin(t, "  two words \n\nlast")
+	for _, want := range []string{"two words", "", "last"} {
+		if line, ok := readLine(); line != want || !ok {
+			t.Errorf("readLine() = %q, %v, want %q", line, ok, want)
+		}
+	}
+	if line, ok := readLine(); ok {
+		t.Errorf("readLine() at the end of the input = %q, want nothing", line)
+	}
+}
+
+func TestPromptsAtEndOfInput(t *testing.T) {
+	withStdin(t, "")
+	if promptYesNo("Yes?") {
+		t.Error("promptYesNo said yes to nothing")
+	}
+	if b := promptBoard(); b.Cols() != NumCols || b.Rows() != NumRows || b.WinLength() != WinLength || b.IsPopOut() {
+		t.Errorf("promptBoard() = %dx%d with %d to win, want the standard board", b.Cols(), b.Rows(), b.WinLength())
+	}
+	if choice, ok := promptOpponent(); ok {
+		t.Errorf("promptOpponent() = %d with nothing to read", choice)
+	}
+
+	s := newTestSession()
+	if move, played, err := promptPlayerMove(s, s.ToMove()); !errors.Is(err, io.EOF) || played {
+		t.Errorf("promptPlayerMove() = %v, %v, %v, want io.EOF", move, played, err)
+	}
+}
+
+func TestPromptPlayerMove(t *testing.T) {
+	s := newTestSession()
+	withStdin(t, "undo\nx\n9\n3\nredo\n")
+	if move, played, err := promptPlayerMove(s, s.ToMove()); move != 3 || !played || err != nil {
+		t.Errorf("promptPlayerMove() = %v, %v, %v, want 3 after nothing to undo and two bad columns", move, played, err)
+	}
+	if err := s.Play(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, played, err := promptPlayerMove(s, s.ToMove()); played || err != nil || len(s.History()) != 1 {
+		t.Errorf("redo: played %v, error %v with %d moves, want the move played again", played, err, len(s.History()))
+	}
+}
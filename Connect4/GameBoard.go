@@ -1,6 +1,7 @@
 package connect4
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -15,52 +16,131 @@ import (
 // ------------------------------------------------------
 // ------------------------------------------------------
 // ------------------------------------------------------
+// NumRows and NumCols are the size of the standard board that NewBoard makes,
+// NewBoardSize makes boards of any size from MinCols x MinRows up to MaxCols x MaxRows.
+// Every size plays the same, but only boards where cols*(rows+1) fits in 64 bits have a BitBoard
+// (see FitsBitBoard), larger ones such as 9x7 and 10x6 lose its speed up of MCTSEngine's playouts.
 const (
 	NumRows = 6
 	NumCols = 7
+
+	MinRows = 4
+	MinCols = 5
+	MaxRows = 9
+	MaxCols = 10
+)
+
+// WinLength is how many pieces in a row win on the boards NewBoard and NewBoardSize make,
+// NewConnectNBoard takes any length from MinWinLength up to the longer side of the board
+const (
+	WinLength    = 4
+	MinWinLength = 3
 )
 
 //------------------------------------------------------
 
-// C4Board holds the board state. Use NumCols / NumRows for array sizes.
+// C4Board holds the board state. The arrays are sized for the largest board,
+// only the first numCols columns and numRows rows are used.
 type C4Board struct {
-	numRows  uint
-	numCols  uint
-	position [NumCols][NumRows]Piece // position[col][row]
-	colCount [NumCols]uint           // how many pieces are in a given column
-	turn     Player                  // who's turn it is to play
+	numRows   uint
+	numCols   uint
+	winLength uint                    // how many pieces in a row win
+	position  [MaxCols][MaxRows]Piece // position[col][row]
+	colCount  [MaxCols]uint           // how many pieces are in a given column
+	turn      Player                  // who's turn it is to play
+	hash      uint64                  // Zobrist hash of the pieces, see Hash
+
+	popOut  bool            // the PopOut variant, see NewPopOutBoard
+	next    Piece           // who plays next, Empty before the first move
+	history *positionRecord // PopOut positions so far, newest first, for spotting repetitions
 }
 
-// Segment is a contiguous four-piece slice used for scoring/checking wins
-type Segment [4]Piece
+// Segment is a contiguous slice of the board, as long as the winning line, used for scoring/checking wins
+type Segment []Piece
 
-// NewBoard returns an initialized Connect4 board
+// NewBoard returns an initialized Connect4 board of the standard NumCols x NumRows size
 func NewBoard() C4Board {
+	b, _ := NewBoardSize(NumCols, NumRows)
+	return b
+}
+
+// NewBoardSize returns an empty board cols wide and rows high where four in a row wins.
+// Returns ErrInvalidSize if the size is outside MinCols x MinRows to MaxCols x MaxRows.
+func NewBoardSize(cols, rows uint) (C4Board, error) {
+	return NewConnectNBoard(cols, rows, WinLength)
+}
+
+// NewConnectNBoard returns an empty board cols wide and rows high where winLength pieces in a row win.
+// Returns ErrInvalidSize if the size is outside MinCols x MinRows to MaxCols x MaxRows,
+// or if winLength is below MinWinLength or longer than both sides of the board.
+func NewConnectNBoard(cols, rows, winLength uint) (C4Board, error) {
+	if cols < MinCols || cols > MaxCols || rows < MinRows || rows > MaxRows {
+		return C4Board{}, fmt.Errorf("%w: %dx%d, boards go from %dx%d to %dx%d",
+			ErrInvalidSize, cols, rows, MinCols, MinRows, MaxCols, MaxRows)
+	}
+	if winLength < MinWinLength || (winLength > cols && winLength > rows) {
+		return C4Board{}, fmt.Errorf("%w: %d in a row can't be played on %dx%d, lines go from %d to %d",
+			ErrInvalidSize, winLength, cols, rows, MinWinLength, max(cols, rows))
+	}
+
 	b := C4Board{
-		numRows: NumRows,
-		numCols: NumCols,
-		turn:    Player{Piece: PlayerIcon},
+		numRows:   rows,
+		numCols:   cols,
+		winLength: winLength,
+		turn:      Player{Piece: PlayerIcon},
 	}
-	return b
+	return b, nil
 }
 
-// Adjusting the turn with the player piece
-func (b C4Board) adjustTurn(player Player) C4Board {
-	b.turn = player
-	return b
+// Cols returns how many columns the board has
+func (board C4Board) Cols() uint {
+	return board.numCols
+}
+
+// Rows returns how many rows the board has
+func (board C4Board) Rows() uint {
+	return board.numRows
+}
+
+// WinLength returns how many pieces in a row win the game
+func (board C4Board) WinLength() uint {
+	return board.winLength
 }
 
 func (b C4Board) String() string {
-	var sb strings.Builder
+	return b.Draw(Glyphs{})
+}
 
+// Draw is String with the pieces drawn as glyphs
+func (b C4Board) Draw(glyphs Glyphs) string {
+	return b.DrawHighlighted(glyphs, nil)
+}
+
+// DrawHighlighted is Draw with the given cells highlighted, for showing the winning line
+func (b C4Board) DrawHighlighted(glyphs Glyphs, cells []Cell) string {
 	if b.numCols == 0 || b.numRows == 0 {
 		return "Empty Board"
 	}
+	return drawBoard(b.numCols, b.numRows, func(col, row int) Piece { return b.position[col][row] }, glyphs, cells)
+}
+
+// drawBoard draws a board of cols by rows with the piece at each cell drawn as its glyph, the top row first.
+// Both kinds of board are drawn by it so they look the same.
+func drawBoard(cols, rows uint, pieceAt func(col, row int) Piece, glyphs Glyphs, highlight []Cell) string {
+	highlighted := make(map[Cell]bool, len(highlight))
+	for _, cell := range highlight {
+		highlighted[cell] = true
+	}
 
-	for i := int(b.numRows) - 1; i >= 0; i-- {
+	var sb strings.Builder
+	for row := int(rows) - 1; row >= 0; row-- {
 		sb.WriteString("|")
-		for j := 0; j < int(b.numCols); j++ {
-			sb.WriteString(b.position[j][i].String())
+		for col := 0; col < int(cols); col++ {
+			glyph := glyphs.Glyph(pieceAt(col, row))
+			if highlighted[Cell{Col: uint(col), Row: uint(row)}] {
+				glyph = highlightOn + glyph + highlightOff
+			}
+			sb.WriteString(glyph)
 			sb.WriteString("|")
 		}
 		sb.WriteString("\n")
@@ -81,30 +161,33 @@ func (b C4Board) String() string {
 //------------------------------------------------------
 //------------------------------------------------------
 
-// GEneric Make Move Functions that is utilized without a column as this will prompt for a column
-// This will then check that the entered in value is a legal move
+// MakePlayerMove asks p for a column on the terminal until a legal move is entered and returns the board
+// with it made. The board comes back unchanged if the game is already over or there is nothing left to read.
+//
+// Deprecated: play games with a Session, Session.Play and TryMove report an illegal move as an error
+// instead of asking again.
 func (board C4Board) MakePlayerMove(p Player) C4Board {
-	//If we have no more moves to make because the game is over then whyy do anything
-	// This is also a failsafe for the recursion to prevent an infinite loop
 	if board.IsGameOver() {
-		fmt.Println("The game is over, unexpected MakeMove call...")
-		fmt.Println("Proceeding without making a move...")
 		return board
 	}
 
-	var col Move
-	fmt.Println("Enter a Column you would like to insert in(0-6): ")
+	fmt.Printf("Enter a Column you would like to insert in(0-%d): \n", board.numCols-1)
 	for {
-		//Going to scan the input, if it is a legal column and is not an error then we want to
-		//make to move and then return the new board
-		if _, err := fmt.Scanln(&col); err == nil && (col <= NumCols || col >= 0) && board.determineIfLegalMove(col) {
-			return board.MakeMove(p, col)
-		} else {
-			fmt.Println("That was not a legal move, please try again: ")
-			return board.MakePlayerMove(p) //Recursively call the function until a legal move is entered
+		answer, ok := readLine()
+		if !ok {
+			return board
 		}
-
-		return board.MakeMove(p, col)
+		col, err := parseMoveInput(answer)
+		if err != nil {
+			fmt.Println("That was not a column number, please try again: ")
+			continue
+		}
+		next, err := board.TryMove(p, col)
+		if err != nil {
+			fmt.Printf("That was not a legal move (%v), please try again: \n", errors.Unwrap(err))
+			continue
+		}
+		return next
 	}
 }
 
@@ -118,10 +201,40 @@ func (board C4Board) determineIfLegalMove(col Move) bool {
 	return false
 }
 
-// MakeMove puts a piece in column col.
-// Returns a copy of the board with the move made.
-// Does not check if the column is full (assumes legal move).
+// TryMove is MakeMove for moves that may not be legal, such as moves typed in by a player,
+// read from a file or received over the network.
+// Instead of making an illegal move it returns the board unchanged and a *MoveError wrapping
+// ErrInvalidPiece, ErrColumnOutOfRange, ErrColumnFull or ErrGameOver.
+func (board C4Board) TryMove(p Player, col Move) (C4Board, error) {
+	var err error
+	switch {
+	case p.Piece != PlayerIcon && p.Piece != CpuIcon:
+		err = ErrInvalidPiece
+	case col.Col() >= board.numCols:
+		err = ErrColumnOutOfRange
+	case board.IsGameOver():
+		err = ErrGameOver
+	case col.IsPop() && !board.canPop(p.Piece, col.Col()):
+		err = ErrCannotPop
+	case !col.IsPop() && board.colCount[col] >= board.numRows:
+		err = ErrColumnFull
+	}
+	if err != nil {
+		return board, &MoveError{Col: col, Piece: p.Piece, Err: err}
+	}
+
+	return board.MakeMove(p, col), nil
+}
+
+// MakeMove puts a piece in column col, or for a PopOut pop takes p's piece off the bottom of it.
+// Returns a copy of the board with the move made, the board itself has no side effects
+// (see Session for running a game and reporting on it).
+// Does not check if the column is full (assumes legal move), use TryMove for moves that may be illegal.
 func (board C4Board) MakeMove(p Player, col Move) C4Board {
+	if col.IsPop() {
+		return board.popPiece(col.Col()).moved(p)
+	}
+
 	b := board
 	piece := p.Piece
 
@@ -129,31 +242,30 @@ func (board C4Board) MakeMove(p Player, col Move) C4Board {
 	// technically this can error however it shouldn't be called if
 	// it isn't a legal move
 	b.position[col][board.colCount[col]] = piece
+	b.hash ^= zobristPieces[col][board.colCount[col]][piece]
 	b.colCount[col]++
 
-	b.turn = p //Adjust the last turn to the current player
-	if board.IsGameOver() {
-		fmt.Println("THAT'S THE GAME FOLKS!")
+	return b.moved(p)
+}
 
-		if board.IsDraw() {
-			fmt.Println("IT'S A DRAW!")
-		} else {
-			fmt.Printf("THE WINNER IS: %s\n", p.Name)
-		}
-		fmt.Println("%s\nFinal Board Position:%s\n")
-		fmt.Println(board.String())
+// moved records that p just moved: whose turn it is and, in PopOut, the position for spotting repetitions
+func (board C4Board) moved(p Player) C4Board {
+	board.turn = p //Adjust the last turn to the current player
+	board.next = p.Piece.opposite()
+	if board.popOut {
+		board.history = &positionRecord{key: board.repetitionKey(), prev: board.history}
 	}
-
-	b.adjustTurn(p)
-	return b
+	return board
 }
 
 // LegalMoves returns all of the current legal moves.
-// Remember, a move is just the column you can play.
+// Remember, a move is just the column you can play, in PopOut it can also be a column to pop.
 func (board C4Board) LegalMoves() []Move {
-	// Creates a slice (start empty) to collect legal moves
-	var legalMoves []Move
+	return board.appendMoves(nil)
+}
 
+// appendMoves appends the legal moves to legalMoves, it doesn't allocate if legalMoves has room for them
+func (board C4Board) appendMoves(legalMoves []Move) []Move {
 	// Appends a possible move if it isn't full
 	var i uint
 	for i = 0; i < board.numCols; i++ {
@@ -162,9 +274,114 @@ func (board C4Board) LegalMoves() []Move {
 		}
 	}
 
+	// In PopOut the player to move can also pop their own piece off the bottom of a column
+	for i = 0; i < board.numCols; i++ {
+		if board.canPop(board.next, i) {
+			legalMoves = append(legalMoves, PopMove(i))
+		}
+	}
+
 	return legalMoves
 }
 
+// isFull reports whether every cell holds a piece
+func (board C4Board) isFull() bool {
+	return board.emptyCells() == 0
+}
+
+// winnerAfter returns who won with move, which mover just played, Empty if nobody did
+func (board C4Board) winnerAfter(move Move, mover Piece) Piece {
+	if move.IsPop() {
+		// a pop moves a whole column so it can finish lines for either player
+		return board.Result().Winner
+	}
+	if board.lastMoveWins(move) {
+		return mover
+	}
+	return Empty
+}
+
+// drawn reports whether the game is drawn, assuming nobody has won
+func (board C4Board) drawn() bool {
+	if board.popOut {
+		return len(board.LegalMoves()) == 0 || board.repeated()
+	}
+	return board.isFull()
+}
+
+// lastMoveWins reports whether the top piece of column col is part of a winning line.
+// Only the lines through that one cell are checked, which is much cheaper than IsWin.
+func (board C4Board) lastMoveWins(col Move) bool {
+	if board.colCount[col] == 0 {
+		return false
+	}
+	row := int(board.colCount[col]) - 1
+	piece := board.position[col][row]
+
+	// count the matching pieces on both sides of the cell along each direction
+	for _, dir := range lineDirections {
+		count := 1
+		for _, sign := range []int{1, -1} {
+			c, r := int(col)+sign*dir[0], row+sign*dir[1]
+			for c >= 0 && c < int(board.numCols) && r >= 0 && r < int(board.numRows) && board.position[c][r] == piece {
+				count++
+				c, r = c+sign*dir[0], r+sign*dir[1]
+			}
+		}
+		if count >= int(board.winLength) {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyCells returns how many cells are left to play in
+func (board C4Board) emptyCells() uint {
+	empty := board.numRows * board.numCols
+	for i := uint(0); i < board.numCols; i++ {
+		empty -= board.colCount[i]
+	}
+	return empty
+}
+
+// OrderedMoves returns the same moves as LegalMoves but ordered from the center
+// column outwards (3, 2, 4, 1, 5, 0, 6 on a standard board).
+// Center columns take part in the most segments so they are usually the strongest moves,
+// searching them first lets alpha-beta prune the rest of the tree much sooner.
+func (board C4Board) OrderedMoves() []Move {
+	var orderedMoves []Move
+
+	var order [MaxCols]uint
+	columns := order[:0] // every column, from the center out
+	center := int(board.numCols) / 2
+	for offset := 0; offset <= center; offset++ {
+		pair := []int{center - offset, center + offset}
+		if offset == 0 {
+			pair = pair[:1]
+		}
+		for _, col := range pair {
+			if col >= 0 && col < int(board.numCols) {
+				columns = append(columns, uint(col))
+			}
+		}
+	}
+
+	for _, col := range columns {
+		if board.colCount[col] < board.numRows {
+			orderedMoves = append(orderedMoves, Move(col))
+		}
+	}
+
+	// PopOut pops go after the drops, also from the center out
+	for _, col := range columns {
+		if board.canPop(board.next, col) {
+			orderedMoves = append(orderedMoves, PopMove(col))
+		}
+	}
+
+	return orderedMoves
+}
+
 // ------------------------------------------------------
 // ------------------------------------------------------
 // ------------------------------------------------------
@@ -202,6 +419,11 @@ func (board C4Board) IsDraw() bool {
 		return true
 	}
 
+	// PopOut games can go round in circles, they are drawn when a position comes up a third time
+	if board.popOut && board.repeated() && !board.IsWin() {
+		return true
+	}
+
 	return false
 }
 
@@ -232,6 +454,10 @@ func (board C4Board) Evaluate(player Piece) float32 {
 	totalScore += CalculateDirection(verticalSegments, player)
 	totalScore += CalculateDirection(diagonalSegments, player)
 
+	if board.popOut {
+		totalScore -= board.cancelledLines(player, horizontalSegments, verticalSegments, diagonalSegments)
+	}
+
 	return totalScore
 }
 
@@ -252,23 +478,43 @@ func segmentEquivalent(segment Segment) bool {
 	return true
 }
 
+// segmentBuffer hands out the segments of a board from a single allocation,
+// scoring a board builds dozens of them so allocating each one on its own adds up
+type segmentBuffer struct {
+	pieces []Piece
+	length int
+}
+
+// newSegmentBuffer makes room for count segments of the board's win length
+func (board C4Board) newSegmentBuffer(count int) *segmentBuffer {
+	length := int(board.winLength)
+	return &segmentBuffer{pieces: make([]Piece, count*length), length: length}
+}
+
+// take returns the segment starting at (col, row) going dCol columns and dRow rows per piece
+func (buf *segmentBuffer) take(board C4Board, col, row, dCol, dRow int) Segment {
+	segment := Segment(buf.pieces[:buf.length:buf.length])
+	buf.pieces = buf.pieces[buf.length:]
+	for i := range segment {
+		segment[i] = board.position[col+i*dCol][row+i*dRow]
+	}
+	return segment
+}
+
 // CheckVertical checks if there is a winning vertical segment
 // it will return immediately if a win is found. If a win is not found
 // it will return all the segments tested and a win status of "false".
 func (board C4Board) CheckVertical() (segments []Segment, win bool) {
 	win = false
-	var segment Segment
+	cols, rows, n := int(board.numCols), int(board.numRows), int(board.winLength)
+	count := max(cols*(rows-n+1), 0)
+	buf := board.newSegmentBuffer(count)
+	segments = make([]Segment, 0, count)
 
 	// Finds all vertical segments and appends to a slice
-	var i, j uint
-	for i = 0; i < board.numCols; i++ {
-		for j = 0; j < board.numRows-3; j++ {
-			segment = Segment{
-				board.position[i][j],
-				board.position[i][j+1],
-				board.position[i][j+2],
-				board.position[i][j+3],
-			}
+	for i := 0; i < cols; i++ {
+		for j := 0; j+n <= rows; j++ {
+			segment := buf.take(board, i, j, 0, 1)
 			segments = append(segments, segment)
 
 			if segmentEquivalent(segment) {
@@ -293,19 +539,15 @@ func (board C4Board) VerticalWin() bool {
 // it will return all the segments tested and a win status of "false".
 func (board C4Board) CheckHorizontal() (segments []Segment, win bool) {
 	win = false
-	var segment Segment
-
-	var i, j uint
+	cols, rows, n := int(board.numCols), int(board.numRows), int(board.winLength)
+	count := max(rows*(cols-n+1), 0)
+	buf := board.newSegmentBuffer(count)
+	segments = make([]Segment, 0, count)
 
 	// Finds all horizontal segments and appends to a slice
-	for i = 0; i < board.numRows; i++ {
-		for j = 0; j < board.numCols-3; j++ {
-			segment = Segment{
-				board.position[j][i],
-				board.position[j+1][i],
-				board.position[j+2][i],
-				board.position[j+3][i],
-			}
+	for i := 0; i < rows; i++ {
+		for j := 0; j+n <= cols; j++ {
+			segment := buf.take(board, j, i, 1, 0)
 			segments = append(segments, segment)
 
 			if segmentEquivalent(segment) {
@@ -330,18 +572,15 @@ func (board C4Board) HorizontalWin() bool {
 // it will return all the segments tested and a win status of "false".
 func (board C4Board) CheckDiagonal() (segments []Segment, win bool) {
 	win = false
-	var segment Segment
+	cols, rows, n := int(board.numCols), int(board.numRows), int(board.winLength)
+	count := 2 * max(cols-n+1, 0) * max(rows-n+1, 0)
+	buf := board.newSegmentBuffer(count)
+	segments = make([]Segment, 0, count)
 
-	var i, j uint
 	// Left to right diagonal checking
-	for i = 0; i < board.numCols-3; i++ {
-		for j = 0; j < board.numRows-3; j++ {
-			segment = Segment{
-				board.position[i][j],
-				board.position[i+1][j+1],
-				board.position[i+2][j+2],
-				board.position[i+3][j+3],
-			}
+	for i := 0; i+n <= cols; i++ {
+		for j := 0; j+n <= rows; j++ {
+			segment := buf.take(board, i, j, 1, 1)
 			segments = append(segments, segment)
 
 			if segmentEquivalent(segment) {
@@ -351,14 +590,9 @@ func (board C4Board) CheckDiagonal() (segments []Segment, win bool) {
 	}
 
 	// Right to left diagonal checking
-	for i = board.numCols - 1; i > 2; i-- {
-		for j = 0; j < board.numRows-3; j++ {
-			segment = Segment{
-				board.position[i][j],
-				board.position[i-1][j+1],
-				board.position[i-2][j+2],
-				board.position[i-3][j+3],
-			}
+	for i := cols - 1; i >= n-1; i-- {
+		for j := 0; j+n <= rows; j++ {
+			segment := buf.take(board, i, j, -1, 1)
 			segments = append(segments, segment)
 
 			if segmentEquivalent(segment) {
@@ -408,24 +642,27 @@ func CalculateScore(segment Segment, player Piece) float32 {
 		}
 	}
 
-	// Closure to handle score calculating
-	score := func() float32 {
-		if pieceCount == 0 {
-			return 0.0
-		} else if pieceCount == 1 {
-			return 1.0
-		} else if pieceCount == 2 {
-			return 5.0
-		} else if pieceCount == 3 {
-			return 50.0
-		} else {
-			return 5000.0
-		}
-	}
 	if pieceToCount != player && pieceToCount != Empty {
 
-		return -score()
+		return -segmentScore(pieceCount, len(segment))
 	}
 
-	return score()
+	return segmentScore(pieceCount, len(segment))
+}
+
+// segmentScore is how much a segment of length cells holding pieceCount pieces of a single player is worth.
+// It goes by how many pieces are missing from the line, so for four in a row
+// 1, 2, 3 and 4 pieces score 1, 5, 50 and 5000, and longer lines score 1 until they are nearly complete.
+func segmentScore(pieceCount, length int) float32 {
+	if pieceCount == 0 {
+		return 0.0
+	} else if pieceCount == length {
+		return 5000.0
+	} else if pieceCount == length-1 {
+		return 50.0
+	} else if pieceCount == length-2 {
+		return 5.0
+	} else {
+		return 1.0
+	}
 }
@@ -1,15 +1,52 @@
 package connect4
 
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
 // Piece represents a player's piece and also turns.
 type Piece uint
+
+// Move is a move on the board. Dropping a piece, the normal move, is just the column number
+// so a column converts straight to a Move. The PopOut variant also lets a player pop their own
+// piece off the bottom of a column, those moves are made with PopMove.
 type Move uint
 
+// popFlag marks a Move as a pop, it is above any column number
+const popFlag Move = 1 << 8
+
+// PopMove returns the move that pops the bottom piece out of column col
+func PopMove(col uint) Move {
+	return Move(col) | popFlag
+}
+
+// Col returns the column the move is played in
+func (m Move) Col() uint {
+	return uint(m &^ popFlag)
+}
+
+// IsPop reports whether the move pops a piece rather than dropping one
+func (m Move) IsPop() bool {
+	return m&popFlag != 0
+}
+
+// String is the column number, with a p in front for a pop
+func (m Move) String() string {
+	if m.IsPop() {
+		return fmt.Sprintf("p%d", m.Col())
+	}
+	return fmt.Sprintf("%d", m.Col())
+}
+
 // Here we will define the player structure to keep track of the player information
 type Player struct {
 	Name      string
 	TurnCount func() int // closure that increments and returns the count
 	Piece     Piece
 	IsHuman   bool
+	Engine    Engine // picks the moves of a computer player
 }
 
 const PlayerIcon Piece = 1
@@ -23,6 +60,12 @@ func (piece Piece) opposite() Piece {
 	return 3 - piece
 }
 
+// Opponent returns a bare Player holding the other piece.
+// The search uses it to play the opponent's replies, it has no name or turn counter.
+func (p Player) Opponent() Player {
+	return Player{Piece: p.Piece.opposite()}
+}
+
 // Description of a piece; useful to be used in the
 // description of a board
 func (piece Piece) String() string {
@@ -36,6 +79,44 @@ func (piece Piece) String() string {
 	}
 }
 
+// Glyphs are the characters boards are drawn with, indexed by Piece. They only change how a board looks,
+// String and the notations always use + and *. The zero value draws pieces the way String does.
+type Glyphs [3]string
+
+// DefaultGlyphs are the glyphs of the first and second player unless others are chosen
+const DefaultGlyphs = "+*"
+
+// NewGlyphs returns the glyphs drawing the first and second player's pieces as first and second.
+// Each has to be a single printable character other than | and they have to differ.
+func NewGlyphs(first, second string) (Glyphs, error) {
+	if err := checkGlyphs(first, second); err != nil {
+		return Glyphs{}, err
+	}
+	return Glyphs{Empty: Empty.String(), PlayerIcon: first, CpuIcon: second}, nil
+}
+
+// Glyph is the character piece is drawn with
+func (g Glyphs) Glyph(piece Piece) string {
+	if piece > CpuIcon || g[piece] == "" {
+		return piece.String()
+	}
+	return g[piece]
+}
+
+// checkGlyphs returns ErrInvalidPiece if first and second can't be used as glyphs, see NewGlyphs
+func checkGlyphs(first, second string) error {
+	for _, glyph := range []string{first, second} {
+		r, size := utf8.DecodeRuneInString(glyph)
+		if size == 0 || size != len(glyph) || r == utf8.RuneError || r == '|' || !unicode.IsGraphic(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%w: %q can't be drawn as a piece", ErrInvalidPiece, glyph)
+		}
+	}
+	if first == second {
+		return fmt.Errorf("%w: both players can't have %q", ErrInvalidPiece, first)
+	}
+	return nil
+}
+
 // // Missplaced
 // // Return if move is in the given list of Moves or not
 // func contains(list []Move, move Move) bool {
@@ -0,0 +1,320 @@
+package connect4
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ------------------------------------------------------
+// Bitboard representation of the board
+// ------------------------------------------------------
+// Each column takes rows+1 bits of a uint64, the lowest bit is the bottom row and the extra
+// bit on top of every column is always empty so lines can't wrap from one column into the next.
+// Bit (col*(rows+1) + row) is the cell position[col][row] of a C4Board.
+// That only works for boards where cols*(rows+1) fits in 64 bits, which includes the
+// standard 7x6 and the 8x7 tournament size but not 9x7, 10x6 or anything with more cells,
+// MCTSEngine plays out games on those with the slower C4Board instead.
+
+// bitLayout holds the masks for one board size and win length
+type bitLayout struct {
+	cols, rows uint
+	winLength  uint
+	colHeight  uint     // bits per column, rows+1
+	bottom     uint64   // the bottom cell of every column
+	top        uint64   // the unused bit above every column
+	full       uint64   // every cell of the board
+	windows    []uint64 // every winLength-cell segment, in the order CheckHorizontal, CheckVertical and CheckDiagonal produce them
+}
+
+// bitLayouts has a layout for every board size that fits in a bitboard and every win length on it,
+// keyed by {cols, rows, winLength}
+var bitLayouts = buildBitLayouts()
+
+func buildBitLayouts() map[[3]uint]*bitLayout {
+	layouts := make(map[[3]uint]*bitLayout)
+	for cols := uint(MinCols); cols <= MaxCols; cols++ {
+		for rows := uint(MinRows); rows <= MaxRows; rows++ {
+			if !FitsBitBoard(cols, rows) {
+				continue
+			}
+			for n := uint(MinWinLength); n <= max(cols, rows); n++ {
+				layouts[[3]uint{cols, rows, n}] = newBitLayout(cols, rows, n)
+			}
+		}
+	}
+	return layouts
+}
+
+func newBitLayout(cols, rows, winLength uint) *bitLayout {
+	l := &bitLayout{cols: cols, rows: rows, winLength: winLength, colHeight: rows + 1}
+	for col := uint(0); col < cols; col++ {
+		l.bottom |= 1 << (col * l.colHeight)
+		l.top |= 1 << (col*l.colHeight + rows)
+	}
+	l.full = l.bottom * ((1 << rows) - 1)
+
+	cell := func(col, row int) uint64 { return 1 << (col*int(l.colHeight) + row) }
+	window := func(col, row, dCol, dRow int) uint64 {
+		var mask uint64
+		for i := 0; i < int(winLength); i++ {
+			mask |= cell(col+i*dCol, row+i*dRow)
+		}
+		return mask
+	}
+	c, r, n := int(cols), int(rows), int(winLength)
+	for row := 0; row < r; row++ {
+		for col := 0; col+n <= c; col++ {
+			l.windows = append(l.windows, window(col, row, 1, 0))
+		}
+	}
+	for col := 0; col < c; col++ {
+		for row := 0; row+n <= r; row++ {
+			l.windows = append(l.windows, window(col, row, 0, 1))
+		}
+	}
+	for col := 0; col+n <= c; col++ {
+		for row := 0; row+n <= r; row++ {
+			l.windows = append(l.windows, window(col, row, 1, 1))
+		}
+	}
+	for col := c - 1; col >= n-1; col-- {
+		for row := 0; row+n <= r; row++ {
+			l.windows = append(l.windows, window(col, row, -1, 1))
+		}
+	}
+	return l
+}
+
+// FitsBitBoard reports whether a board of the given size can be stored as a BitBoard
+func FitsBitBoard(cols, rows uint) bool {
+	return cols*(rows+1) <= 64
+}
+
+// BitBoard is a C4Board stored as one bit mask per piece.
+// It behaves the same as C4Board but win checks and evaluation are a few
+// shifts and popcounts instead of building segment slices.
+type BitBoard struct {
+	layout *bitLayout
+	masks  [3]uint64     // masks[piece] has a bit set for every cell holding piece, masks[Empty] is unused
+	height [MaxCols]uint // the bit index of the next free cell in each column
+	turn   Player        // who's turn it is to play
+}
+
+// NewBitBoard returns an empty standard size bitboard, the same position as NewBoard
+func NewBitBoard() BitBoard {
+	bb, _ := NewBitBoardSize(NumCols, NumRows)
+	return bb
+}
+
+// NewBitBoardSize returns an empty bitboard cols wide and rows high where four in a row wins.
+// Returns ErrInvalidSize for a size NewBoardSize doesn't allow and
+// ErrUnsupportedSize for one that is too large for a bitboard.
+func NewBitBoardSize(cols, rows uint) (BitBoard, error) {
+	return NewConnectNBitBoard(cols, rows, WinLength)
+}
+
+// NewConnectNBitBoard is NewConnectNBoard for bitboards, returning the same errors as NewBitBoardSize
+func NewConnectNBitBoard(cols, rows, winLength uint) (BitBoard, error) {
+	if _, err := NewConnectNBoard(cols, rows, winLength); err != nil {
+		return BitBoard{}, err
+	}
+	layout, ok := bitLayouts[[3]uint{cols, rows, winLength}]
+	if !ok {
+		return BitBoard{}, fmt.Errorf("%w: %dx%d does not fit in a bitboard", ErrUnsupportedSize, cols, rows)
+	}
+
+	bb := BitBoard{layout: layout, turn: Player{Piece: PlayerIcon}}
+	for col := uint(0); col < cols; col++ {
+		bb.height[col] = col * layout.colHeight
+	}
+	return bb, nil
+}
+
+// ToBitBoard converts a C4Board into the equivalent BitBoard.
+// Returns ErrUnsupportedSize if the board is too large for a bitboard
+// and ErrUnsupportedVariant for a PopOut board.
+func ToBitBoard(board C4Board) (BitBoard, error) {
+	if board.popOut {
+		return BitBoard{}, fmt.Errorf("%w: bitboards don't play PopOut", ErrUnsupportedVariant)
+	}
+	bb, err := NewConnectNBitBoard(board.numCols, board.numRows, board.winLength)
+	if err != nil {
+		return BitBoard{}, err
+	}
+	bb.turn = board.turn
+	for col := uint(0); col < board.numCols; col++ {
+		for row := uint(0); row < board.colCount[col]; row++ {
+			bb.masks[board.position[col][row]] |= 1 << bb.height[col]
+			bb.height[col]++
+		}
+	}
+	return bb, nil
+}
+
+// ToC4Board converts the bitboard back into a C4Board
+func (bb BitBoard) ToC4Board() C4Board {
+	board, _ := NewConnectNBoard(bb.layout.cols, bb.layout.rows, bb.layout.winLength)
+	for col := 0; col < int(bb.layout.cols); col++ {
+		for row := 0; row < int(bb.layout.rows); row++ {
+			if piece := bb.pieceAt(col, row); piece != Empty {
+				board.position[col][row] = piece
+				board.hash ^= zobristPieces[col][row][piece]
+				board.colCount[col]++
+			}
+		}
+	}
+	board.turn = bb.turn
+	return board
+}
+
+// pieceAt returns the piece in a cell, Empty if there is none
+func (bb BitBoard) pieceAt(col, row int) Piece {
+	bit := uint64(1) << (col*int(bb.layout.colHeight) + row)
+	switch {
+	case bb.masks[PlayerIcon]&bit != 0:
+		return PlayerIcon
+	case bb.masks[CpuIcon]&bit != 0:
+		return CpuIcon
+	default:
+		return Empty
+	}
+}
+
+// MakeMove puts a piece in column col.
+// Returns a copy of the board with the move made.
+// Does not check if the column is full (assumes legal move).
+func (bb BitBoard) MakeMove(p Player, col Move) BitBoard {
+	bb.masks[p.Piece] |= 1 << bb.height[col]
+	bb.height[col]++
+	bb.turn = p
+	return bb
+}
+
+// canPlay reports whether column col still has room
+func (bb BitBoard) canPlay(col int) bool {
+	return (1<<bb.height[col])&bb.layout.top == 0
+}
+
+// winnerAfter returns who won with move, which mover just played, Empty if nobody did
+func (bb BitBoard) winnerAfter(move Move, mover Piece) Piece {
+	if bb.lastMoveWins(move) {
+		return mover
+	}
+	return Empty
+}
+
+// drawn reports whether the game is drawn, assuming nobody has won
+func (bb BitBoard) drawn() bool {
+	return bb.isFull()
+}
+
+// lastMoveWins reports whether the top piece of column col is part of a winning line.
+// Only the lines through that one cell are checked, like C4Board.lastMoveWins.
+func (bb BitBoard) lastMoveWins(col Move) bool {
+	row := int(bb.height[col]) - int(col)*int(bb.layout.colHeight) - 1
+	if row < 0 {
+		return false
+	}
+	cell := uint64(1) << (bb.height[col] - 1)
+	mask := bb.masks[bb.pieceAt(int(col), row)]
+
+	// count the matching pieces on both sides of the cell along each direction,
+	// the empty bit on top of every column stops a run from wrapping into the next one
+	h := bb.layout.colHeight
+	for _, shift := range []uint{h, 1, h + 1, h - 1} {
+		count := uint(1)
+		for next := cell << shift; next&mask != 0; next <<= shift {
+			count++
+		}
+		for next := cell >> shift; next&mask != 0; next >>= shift {
+			count++
+		}
+		if count >= bb.layout.winLength {
+			return true
+		}
+	}
+	return false
+}
+
+// LegalMoves returns every column that isn't full, lowest column first like C4Board.LegalMoves
+func (bb BitBoard) LegalMoves() []Move {
+	return bb.appendMoves(nil)
+}
+
+// appendMoves appends the legal moves to legalMoves, it doesn't allocate if legalMoves has room for them
+func (bb BitBoard) appendMoves(legalMoves []Move) []Move {
+	for col := 0; col < int(bb.layout.cols); col++ {
+		if bb.canPlay(col) {
+			legalMoves = append(legalMoves, Move(col))
+		}
+	}
+	return legalMoves
+}
+
+// hasLine reports whether mask contains a winning line in any direction
+func (bb BitBoard) hasLine(mask uint64) bool {
+	h := bb.layout.colHeight
+	// horizontal, vertical, and the two diagonals
+	for _, shift := range []uint{h, 1, h + 1, h - 1} {
+		// after i steps a bit is still set if it starts a run of i+1 pieces
+		run := mask
+		for i := uint(1); i < bb.layout.winLength && run != 0; i++ {
+			run &= mask >> (i * shift)
+		}
+		if run != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWin reports whether either player has a winning line
+func (bb BitBoard) IsWin() bool {
+	return bb.hasLine(bb.masks[PlayerIcon]) || bb.hasLine(bb.masks[CpuIcon])
+}
+
+// isFull reports whether every cell holds a piece
+func (bb BitBoard) isFull() bool {
+	return bb.masks[PlayerIcon]|bb.masks[CpuIcon] == bb.layout.full
+}
+
+// IsDraw reports whether the board is full without a winner
+func (bb BitBoard) IsDraw() bool {
+	return bb.isFull() && !bb.IsWin()
+}
+
+// IsGameOver reports whether the game has been won or drawn
+func (bb BitBoard) IsGameOver() bool {
+	return bb.IsWin() || bb.IsDraw()
+}
+
+// Evaluate scores the position for player exactly like C4Board.Evaluate,
+// counting the pieces in every window as long as the winning line with a popcount
+func (bb BitBoard) Evaluate(player Piece) float32 {
+	var totalScore float32
+
+	mine, theirs := bb.masks[player], bb.masks[player.opposite()]
+	for _, window := range bb.layout.windows {
+		mineCount := bits.OnesCount64(mine & window)
+		theirCount := bits.OnesCount64(theirs & window)
+		switch {
+		case mineCount > 0 && theirCount > 0:
+			continue
+		case mineCount > 0:
+			totalScore += segmentScore(mineCount, int(bb.layout.winLength))
+		case theirCount > 0:
+			totalScore -= segmentScore(theirCount, int(bb.layout.winLength))
+		}
+	}
+
+	return totalScore
+}
+
+func (bb BitBoard) String() string {
+	return bb.Draw(Glyphs{})
+}
+
+// Draw is String with the pieces drawn as glyphs, the way C4Board draws them
+func (bb BitBoard) Draw(glyphs Glyphs) string {
+	return drawBoard(bb.layout.cols, bb.layout.rows, bb.pieceAt, glyphs, nil)
+}
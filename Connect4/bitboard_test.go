@@ -0,0 +1,169 @@
+package connect4
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomBoards plays count random games and returns every position reached along the way
+func randomBoards(seed int64, count int) []C4Board {
+	return randomConnectNBoards(seed, count, NumCols, NumRows, WinLength)
+}
+
+// randomConnectNBoards is randomBoards on a cols x rows board where winLength in a row wins
+func randomConnectNBoards(seed int64, count int, cols, rows, winLength uint) []C4Board {
+	r := rand.New(rand.NewSource(seed))
+	var boards []C4Board
+	for i := 0; i < count; i++ {
+		b, _ := NewConnectNBoard(cols, rows, winLength)
+		p := Player{Piece: PlayerIcon}
+		for !b.IsGameOver() {
+			moves := b.LegalMoves()
+			b = b.MakeMove(p, moves[r.Intn(len(moves))])
+			p = p.Opponent()
+			boards = append(boards, b)
+		}
+	}
+	return boards
+}
+
+func TestBitBoardMatchesC4Board(t *testing.T) {
+	for _, b := range randomBoards(1, 200) {
+		checkBitBoardMatches(t, b)
+	}
+}
+
+func TestBitBoardMatchesC4BoardSizes(t *testing.T) {
+	// cols, rows and win length
+	sizes := [][3]uint{{MinCols, MinRows, 4}, {5, 4, 4}, {6, 5, 4}, {8, 7, 4}, {9, 6, 4},
+		{MinCols, MinRows, 3}, {5, 5, 3}, {8, 7, 5}, {9, 6, 6}, {6, 5, 6}}
+	for i, size := range sizes {
+		for _, b := range randomConnectNBoards(int64(10+i), 50, size[0], size[1], size[2]) {
+			checkBitBoardMatches(t, b)
+		}
+	}
+}
+
+// checkBitBoardMatches fails the test if the bitboard of b disagrees with b
+func checkBitBoardMatches(t *testing.T, b C4Board) {
+	t.Helper()
+	bb, err := ToBitBoard(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bb.String() != b.String() {
+		t.Fatalf("String() differs\n%s\n%s", bb, b)
+	}
+	if bb.IsWin() != b.IsWin() || bb.IsDraw() != b.IsDraw() {
+		t.Fatalf("IsWin/IsDraw differ on\n%s", b)
+	}
+	if !reflect.DeepEqual(bb.LegalMoves(), b.LegalMoves()) {
+		t.Fatalf("LegalMoves differ on\n%s", b)
+	}
+	for col := Move(0); col < Move(b.Cols()); col++ {
+		if bb.lastMoveWins(col) != b.lastMoveWins(col) {
+			t.Fatalf("lastMoveWins(%d) differs on\n%s", col, b)
+		}
+	}
+	for _, piece := range []Piece{PlayerIcon, CpuIcon} {
+		if got, want := bb.Evaluate(piece), b.Evaluate(piece); got != want {
+			t.Fatalf("Evaluate(%v) = %v, want %v on\n%s", piece, got, want, b)
+		}
+	}
+	if back := bb.ToC4Board(); back.String() != b.String() || back.Hash() != b.Hash() {
+		t.Fatalf("round trip changed the board\n%s\n%s", back, b)
+	}
+}
+
+func TestBitBoardLastMoveWins(t *testing.T) {
+	// the first player's four in column 1 is not a line through the piece dropped in column 7 after it
+	b, _ := playMoves(t, "1212121")
+	b = b.MakeMove(Player{Piece: CpuIcon}, 2).MakeMove(Player{Piece: PlayerIcon}, 6)
+	bb, err := ToBitBoard(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bb.lastMoveWins(0) || bb.lastMoveWins(6) || bb.lastMoveWins(2) {
+		t.Errorf("lastMoveWins(1, 7, 3) = %v, %v, %v, want true, false, false\n%s",
+			bb.lastMoveWins(0), bb.lastMoveWins(6), bb.lastMoveWins(2), bb)
+	}
+}
+
+func TestBoardSizeLimits(t *testing.T) {
+	if _, err := NewBoardSize(MinCols-1, NumRows); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("NewBoardSize(%d, %d) error = %v, want ErrInvalidSize", MinCols-1, NumRows, err)
+	}
+	if _, err := NewBoardSize(NumCols, MaxRows+1); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("NewBoardSize(%d, %d) error = %v, want ErrInvalidSize", NumCols, MaxRows+1, err)
+	}
+
+	b, err := NewBoardSize(MaxCols, MaxRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ToBitBoard(b); !errors.Is(err, ErrUnsupportedSize) {
+		t.Errorf("ToBitBoard on %dx%d error = %v, want ErrUnsupportedSize", MaxCols, MaxRows, err)
+	}
+	if got := len(b.LegalMoves()); got != MaxCols {
+		t.Errorf("%dx%d board has %d legal moves, want %d", MaxCols, MaxRows, got, MaxCols)
+	}
+}
+
+func BenchmarkC4BoardEvaluate(b *testing.B) {
+	boards := randomBoards(2, 20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		boards[i%len(boards)].Evaluate(PlayerIcon)
+	}
+}
+
+func BenchmarkBitBoardEvaluate(b *testing.B) {
+	var boards []BitBoard
+	for _, board := range randomBoards(2, 20) {
+		bb, _ := ToBitBoard(board)
+		boards = append(boards, bb)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		boards[i%len(boards)].Evaluate(PlayerIcon)
+	}
+}
+
+func BenchmarkC4BoardIsWin(b *testing.B) {
+	boards := randomBoards(3, 20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		boards[i%len(boards)].IsWin()
+	}
+}
+
+func BenchmarkBitBoardIsWin(b *testing.B) {
+	var boards []BitBoard
+	for _, board := range randomBoards(3, 20) {
+		bb, _ := ToBitBoard(board)
+		boards = append(boards, bb)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		boards[i%len(boards)].IsWin()
+	}
+}
+
+func BenchmarkC4BoardMakeMove(b *testing.B) {
+	board := NewBoard()
+	p := Player{Piece: PlayerIcon}
+	for i := 0; i < b.N; i++ {
+		board.MakeMove(p, Move(i%NumCols))
+	}
+}
+
+func BenchmarkBitBoardMakeMove(b *testing.B) {
+	board := NewBitBoard()
+	p := Player{Piece: PlayerIcon}
+	for i := 0; i < b.N; i++ {
+		board.MakeMove(p, Move(i%NumCols))
+	}
+}
@@ -0,0 +1,119 @@
+package connect4
+
+import (
+	"context"
+	"time"
+)
+
+// Search searches the position with iterative deepening: it runs a full search
+// at depth 0, then 1, then 2 and so on up to maxDepth, until ctx is cancelled or its deadline passes.
+// A maxDepth of 0 keeps deepening until the search reaches the end of the game.
+// Each iteration searches the previous iteration's best move first and the table carries what
+// was learned from one iteration to the next, so the repeated shallow searches cost very little.
+// The result is from the deepest iteration that finished, an iteration cut short by ctx is thrown away,
+// but its nodes and time are still counted. If not even depth 0 finished the move is the
+// most central legal move and the result has no score or principal variation.
+func Search(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable) SearchResult {
+	return searchReporting(ctx, b, p, maxDepth, tt, nil)
+}
+
+// searchReporting is Search that calls report with the result of each finished iteration, see ConcurrentSearchReporting
+func searchReporting(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable, report func(SearchResult)) SearchResult {
+	return deepen(b, p, maxDepth, tt, report, func(depth uint, moves []Move) (Eval, uint64, bool) {
+		s := searcher{ctx: ctx, tt: tt}
+		best, ok := s.searchRoot(b, p, depth, moves)
+		return best, s.nodes, ok
+	})
+}
+
+// ConcurrentSearch is Search with every iteration searched like ConcurrentFindBestMove,
+// one goroutine per legal move.
+// When ctx is done every goroutine of the running iteration stops and has returned
+// before ConcurrentSearch does.
+func ConcurrentSearch(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable) SearchResult {
+	return ConcurrentSearchReporting(ctx, b, p, maxDepth, tt, nil)
+}
+
+// ConcurrentSearchReporting is ConcurrentSearch that calls report with the result so far after
+// every iteration that finishes, such as to show the search deepening while it runs
+func ConcurrentSearchReporting(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable, report func(SearchResult)) SearchResult {
+	return deepen(b, p, maxDepth, tt, report, func(depth uint, moves []Move) (Eval, uint64, bool) {
+		return concurrentSearch(ctx, b, p, depth, tt, false, moves)
+	})
+}
+
+// FindBestMoveContext is Search for callers that only want the move and the depth it was found at
+func FindBestMoveContext(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable) (Move, uint) {
+	result := Search(ctx, b, p, maxDepth, tt)
+	return result.Move, result.Depth
+}
+
+// ConcurrentFindBestMoveContext is ConcurrentSearch for callers that only want the move and the depth it was found at
+func ConcurrentFindBestMoveContext(ctx context.Context, b C4Board, p Player, maxDepth uint, tt *TranspositionTable) (Move, uint) {
+	result := ConcurrentSearch(ctx, b, p, maxDepth, tt)
+	return result.Move, result.Depth
+}
+
+// FindBestMoveWithin thinks about the position for at most limit and returns the best move it found
+func FindBestMoveWithin(b C4Board, p Player, limit time.Duration) Move {
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+
+	result := ConcurrentSearch(ctx, b, p, 0, NewTranspositionTable(DefaultTableSize))
+	return result.Move
+}
+
+// How deep an unlimited search of a PopOut position goes at most
+const popOutMaxDepth = 64
+
+// deepen is the iterative deepening loop shared by the searches,
+// search runs a single iteration, returning the nodes it searched and false if it was cancelled.
+// report, if not nil, is called after each iteration that finishes.
+func deepen(b C4Board, p Player, maxDepth uint, tt *TranspositionTable, report func(SearchResult), search func(depth uint, moves []Move) (Eval, uint64, bool)) SearchResult {
+	start := time.Now()
+	var result SearchResult
+
+	moves := b.OrderedMoves()
+	if len(moves) == 0 {
+		return result
+	}
+	result.Move = moves[0]
+
+	// The root move fills one cell, searching deeper than the cells left over is wasted work.
+	// PopOut games go on after the board is full so only popOutMaxDepth stops them.
+	lastDepth := b.emptyCells() - 1
+	if b.popOut {
+		lastDepth = popOutMaxDepth
+	}
+	if maxDepth != 0 && maxDepth < lastDepth {
+		lastDepth = maxDepth
+	}
+
+	for depth := uint(0); depth <= lastDepth; depth++ {
+		eval, nodes, ok := search(depth, bestFirst(moves, result.Move))
+		result.Nodes += nodes
+		if !ok {
+			break
+		}
+		result.Move, result.Score, result.Depth = eval.m, eval.f, depth
+		result.PV = principalVariation(b, p, eval.m, depth, tt)
+		if report != nil {
+			result.Elapsed = time.Since(start)
+			report(result)
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	return result
+}
+
+// bestFirst returns moves with best moved to the front
+func bestFirst(moves []Move, best Move) []Move {
+	ordered := []Move{best}
+	for _, move := range moves {
+		if move != best {
+			ordered = append(ordered, move)
+		}
+	}
+	return ordered
+}
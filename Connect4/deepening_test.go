@@ -0,0 +1,148 @@
+package connect4
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// How long past its deadline a cancelled search may take to return
+const cancelSlack = 500 * time.Millisecond
+
+func TestDeepenKeepsLastFinishedIteration(t *testing.T) {
+	b, p := playMoves(t, "44")
+	var searched []uint
+	result := deepen(b, p, 0, nil, nil, func(depth uint, moves []Move) (Eval, uint64, bool) {
+		searched = append(searched, depth)
+		if depth == 3 {
+			return Eval{m: 6, f: 99}, 10, false // cut short, thrown away
+		}
+		return Eval{m: Move(depth), f: float32(depth)}, 10, true
+	})
+
+	if result.Move != 2 || result.Score != 2 || result.Depth != 2 {
+		t.Errorf("result move %v score %v depth %d, want the depth 2 iteration's move 2", result.Move, result.Score, result.Depth)
+	}
+	if result.Nodes != 40 {
+		t.Errorf("result counted %d nodes, want all 40 including the cancelled iteration", result.Nodes)
+	}
+	if len(searched) != 4 {
+		t.Errorf("searched depths %v, want 0 to 3", searched)
+	}
+}
+
+func TestSearchNeverFinished(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, moves := range []string{"", "444444"} {
+		b, p := playMoves(t, moves)
+		want := b.OrderedMoves()[0]
+		for name, search := range map[string]func(context.Context, C4Board, Player, uint, *TranspositionTable) SearchResult{
+			"Search":           Search,
+			"ConcurrentSearch": ConcurrentSearch,
+		} {
+			result := search(ctx, b, p, 0, NewTranspositionTable(1024))
+			if result.Move != want || result.Depth != 0 || result.Score != 0 || result.PV != nil {
+				t.Errorf("%q %s: %+v, want the central move %v and nothing else", moves, name, result, want)
+			}
+		}
+	}
+}
+
+// reportingSearches are the deepening searches that report each finished iteration
+var reportingSearches = map[string]func(context.Context, C4Board, Player, uint, *TranspositionTable, func(SearchResult)) SearchResult{
+	"Search":           searchReporting,
+	"ConcurrentSearch": ConcurrentSearchReporting,
+}
+
+func TestSearchCancelled(t *testing.T) {
+	b, p := playMoves(t, "4453")
+	for name, search := range reportingSearches {
+		// cancelled as soon as depth 2 is reported, depth 3 can't finish
+		ctx, cancel := context.WithCancel(context.Background())
+		var reported []SearchResult
+		result := search(ctx, b, p, 0, NewTranspositionTable(DefaultTableSize), func(r SearchResult) {
+			reported = append(reported, r)
+			if r.Depth == 2 {
+				cancel()
+			}
+		})
+		cancel()
+
+		if len(reported) != 3 {
+			t.Fatalf("%s: %d iterations reported, want depths 0 to 2", name, len(reported))
+		}
+		for i, r := range reported {
+			if r.Depth != uint(i) {
+				t.Errorf("%s: report %d at depth %d", name, i, r.Depth)
+			}
+		}
+		last := reported[len(reported)-1]
+		if result.Depth != 2 || result.Move != last.Move || result.Score != last.Score {
+			t.Errorf("%s: result move %v score %v depth %d, want the depth 2 iteration's move %v score %v",
+				name, result.Move, result.Score, result.Depth, last.Move, last.Score)
+		}
+		if !b.determineIfLegalMove(result.Move) || len(result.PV) == 0 || result.PV[0] != result.Move {
+			t.Errorf("%s: move %v with pv %v", name, result.Move, result.PV)
+		}
+	}
+}
+
+// cancelledAfter is a context that is only cancelled once Err has been asked more than n times
+type cancelledAfter struct {
+	context.Context
+	n     int32
+	calls atomic.Int32
+}
+
+func (c *cancelledAfter) Err() error {
+	if c.calls.Add(1) > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestConcurrentSearchKeepsFinishedIteration(t *testing.T) {
+	b, p := playMoves(t, "4")
+	moves := b.LegalMoves()
+	want := ConcurrentFindBestMoveTT(b, p, 1, NewTranspositionTable(1024))
+
+	// a depth 1 search of each move checks the context once, it is cancelled by the time they all returned
+	ctx := &cancelledAfter{Context: context.Background(), n: int32(len(moves))}
+	best, _, ok := concurrentSearch(ctx, b, p, 1, NewTranspositionTable(1024), false, moves)
+	if ctx.Err() == nil {
+		t.Fatal("the context was never cancelled")
+	}
+	if !ok || best.m != want {
+		t.Errorf("concurrentSearch = %v, %v, want the finished search's move %v", best.m, ok, want)
+	}
+}
+
+func TestConcurrentSearchStopsItsGoroutines(t *testing.T) {
+	b, p := playMoves(t, "4")
+	tt := NewTranspositionTable(DefaultTableSize)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	ConcurrentSearch(ctx, b, p, 0, tt)
+
+	// every goroutine probes the table for each position it searches, a table left alone has none running
+	hits, misses := tt.Stats()
+	time.Sleep(50 * time.Millisecond)
+	if laterHits, laterMisses := tt.Stats(); laterHits != hits || laterMisses != misses {
+		t.Errorf("the table was probed %d more times after ConcurrentSearch returned", laterHits+laterMisses-hits-misses)
+	}
+}
+
+func TestFindBestMoveWithin(t *testing.T) {
+	b, p := playMoves(t, "112233")
+	start := time.Now()
+	if move := FindBestMoveWithin(b, p, 50*time.Millisecond); move != 3 {
+		t.Errorf("FindBestMoveWithin = %v, want the winning move 4", move)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond+cancelSlack {
+		t.Errorf("FindBestMoveWithin took %v", elapsed)
+	}
+}
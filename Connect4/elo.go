@@ -0,0 +1,113 @@
+package connect4
+
+import (
+	"fmt"
+	"math"
+)
+
+// ------------------------------------------------------
+// Elo - how much stronger one engine is than another
+// ------------------------------------------------------
+// Results between two engines are turned into an Elo difference with the logistic curve:
+// an engine scoring p of the points is 400 * log10(p / (1 - p)) stronger than its opponents.
+// The error bars are the 95% confidence interval worked out from the spread of the game results,
+// wins, draws and losses scoring 1, 1/2 and 0.
+
+// How many standard errors either side of the score the error bars cover, 95% of results
+const eloConfidence = 1.959964
+
+// Score counts the results of games from one side's point of view
+type Score struct {
+	Wins, Draws, Losses int
+}
+
+// Games is how many games the score counts
+func (s Score) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Points is a point for a win and half a point for a draw
+func (s Score) Points() float64 {
+	return float64(s.Wins) + float64(s.Draws)/2
+}
+
+// Fraction is the share of the points taken, 0.5 without any games
+func (s Score) Fraction() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return s.Points() / float64(s.Games())
+}
+
+// Add returns the two scores counted together
+func (s Score) Add(other Score) Score {
+	return Score{Wins: s.Wins + other.Wins, Draws: s.Draws + other.Draws, Losses: s.Losses + other.Losses}
+}
+
+// Reversed is the score from the other side's point of view
+func (s Score) Reversed() Score {
+	return Score{Wins: s.Losses, Draws: s.Draws, Losses: s.Wins}
+}
+
+// Elo returns the Elo difference the score shows and the margin of error either side of it.
+// A side that took all or none of the points is infinitely stronger or weaker, as is the margin
+// of a score that can't be told apart from one of those.
+func (s Score) Elo() (diff, margin float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, math.Inf(1)
+	}
+	p := s.Fraction()
+	stderr := math.Sqrt(s.variance() / n)
+
+	low, high := p-eloConfidence*stderr, p+eloConfidence*stderr
+	if low <= 0 || high >= 1 {
+		margin = math.Inf(1)
+	} else {
+		margin = (EloDifference(high) - EloDifference(low)) / 2
+	}
+	return EloDifference(p), margin
+}
+
+// variance is the spread of the points taken in a game around the Fraction
+func (s Score) variance() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	p := s.Fraction()
+	return (float64(s.Wins)*(1-p)*(1-p) + float64(s.Draws)*(0.5-p)*(0.5-p) + float64(s.Losses)*p*p) / float64(s.Games())
+}
+
+// String shows the score as wins-draws-losses
+func (s Score) String() string {
+	return fmt.Sprintf("%d-%d-%d", s.Wins, s.Draws, s.Losses)
+}
+
+// EloDifference is how much stronger a side taking fraction of the points is, see ExpectedScore
+func EloDifference(fraction float64) float64 {
+	return -400 * math.Log10(1/fraction-1)
+}
+
+// ExpectedScore is the fraction of the points a side diff Elo stronger is expected to take
+func ExpectedScore(diff float64) float64 {
+	return 1 / (1 + math.Pow(10, -diff/400))
+}
+
+// formatElo writes an Elo difference and its margin like "+35.2 ± 20.1"
+func formatElo(diff, margin float64) string {
+	return fmt.Sprintf("%s ± %s", formatEloValue(diff, true), formatEloValue(margin, false))
+}
+
+func formatEloValue(v float64, signed bool) string {
+	switch {
+	case math.IsInf(v, 1) && signed:
+		return "+inf"
+	case math.IsInf(v, 1):
+		return "inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	case signed:
+		return fmt.Sprintf("%+.1f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
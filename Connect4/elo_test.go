@@ -0,0 +1,34 @@
+package connect4
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreElo(t *testing.T) {
+	tests := []struct {
+		score        Score
+		diff, margin float64
+	}{
+		{Score{Wins: 10, Draws: 0, Losses: 10}, 0, 163.3},
+		{Score{Wins: 60, Draws: 20, Losses: 20}, 147.2, 66.0},
+		{Score{Wins: 20, Draws: 20, Losses: 60}, -147.2, 66.0},
+		{Score{Draws: 10}, 0, 0},
+	}
+	for _, tt := range tests {
+		diff, margin := tt.score.Elo()
+		if math.Abs(diff-tt.diff) > 0.1 || math.Abs(margin-tt.margin) > 0.1 {
+			t.Errorf("%v.Elo() = %.1f ± %.1f, want %.1f ± %.1f", tt.score, diff, margin, tt.diff, tt.margin)
+		}
+	}
+
+	if diff, margin := (Score{Wins: 5}).Elo(); !math.IsInf(diff, 1) || !math.IsInf(margin, 1) {
+		t.Errorf("a clean sweep is %v ± %v, want +inf ± inf", diff, margin)
+	}
+	if got := formatElo((Score{Losses: 3}).Elo()); got != "-inf ± inf" {
+		t.Errorf("formatElo of a whitewash = %q", got)
+	}
+	if got := ExpectedScore(EloDifference(0.75)); math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("ExpectedScore(EloDifference(0.75)) = %v", got)
+	}
+}
@@ -0,0 +1,209 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ------------------------------------------------------
+// Engines - the computer opponents
+// ------------------------------------------------------
+
+// Engine picks a move for player p on board b.
+// Any type with these methods can be plugged into a game as a computer player.
+type Engine interface {
+	Name() string
+	BestMove(b C4Board, p Player) Move
+}
+
+// ScoringEngine is an Engine that can also say how good it thinks its move is.
+// Scores are only comparable between moves of the same engine.
+type ScoringEngine interface {
+	Engine
+	BestMoveScore(b C4Board, p Player) (Move, float32)
+}
+
+// SearchEngine is an Engine that can report everything its search found, see SearchResult
+type SearchEngine interface {
+	Engine
+	Search(b C4Board, p Player) SearchResult
+}
+
+// MiniMaxEngine searches a fixed depth with ConcurrentFindBestMove.
+// It keeps its transposition table from one move to the next.
+type MiniMaxEngine struct {
+	Depth uint
+	table *TranspositionTable
+}
+
+// NewMiniMaxEngine returns a MiniMaxEngine looking depth plies past its own move
+func NewMiniMaxEngine(depth uint) *MiniMaxEngine {
+	return &MiniMaxEngine{Depth: depth, table: NewTranspositionTable(DefaultTableSize)}
+}
+
+func (e *MiniMaxEngine) Name() string {
+	return fmt.Sprintf("Minimax (depth %d)", e.Depth)
+}
+
+func (e *MiniMaxEngine) BestMove(b C4Board, p Player) Move {
+	move, _ := e.BestMoveScore(b, p)
+	return move
+}
+
+func (e *MiniMaxEngine) BestMoveScore(b C4Board, p Player) (Move, float32) {
+	result := e.Search(b, p)
+	return result.Move, result.Score
+}
+
+func (e *MiniMaxEngine) Search(b C4Board, p Player) SearchResult {
+	start := time.Now()
+	best, nodes, _ := concurrentSearch(context.Background(), b, p, e.Depth, e.table, true, b.LegalMoves())
+
+	return SearchResult{
+		Move:    best.m,
+		Score:   best.f,
+		PV:      principalVariation(b, p, best.m, e.Depth, e.table),
+		Nodes:   nodes,
+		Depth:   e.Depth,
+		Elapsed: time.Since(start),
+	}
+}
+
+// TimedEngine searches with iterative deepening until it runs out of time or reaches MaxDepth
+type TimedEngine struct {
+	ThinkTime time.Duration
+	MaxDepth  uint // 0 for no limit
+	table     *TranspositionTable
+}
+
+// NewTimedEngine returns a TimedEngine that thinks for thinkTime per move, at most maxDepth deep
+func NewTimedEngine(thinkTime time.Duration, maxDepth uint) *TimedEngine {
+	return &TimedEngine{ThinkTime: thinkTime, MaxDepth: maxDepth, table: NewTranspositionTable(DefaultTableSize)}
+}
+
+func (e *TimedEngine) Name() string {
+	if e.MaxDepth == 0 {
+		return fmt.Sprintf("Iterative deepening (%v)", e.ThinkTime)
+	}
+	return fmt.Sprintf("Iterative deepening (%v, depth %d)", e.ThinkTime, e.MaxDepth)
+}
+
+func (e *TimedEngine) BestMove(b C4Board, p Player) Move {
+	move, _ := e.BestMoveScore(b, p)
+	return move
+}
+
+func (e *TimedEngine) BestMoveScore(b C4Board, p Player) (Move, float32) {
+	result := e.Search(b, p)
+	return result.Move, result.Score
+}
+
+func (e *TimedEngine) Search(b C4Board, p Player) SearchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ThinkTime)
+	defer cancel()
+
+	return ConcurrentSearch(ctx, b, p, e.MaxDepth, e.table)
+}
+
+// RandomEngine plays a random legal move
+type RandomEngine struct {
+	rng *rand.Rand
+}
+
+// NewRandomEngine returns a RandomEngine, the same seed plays the same moves
+func NewRandomEngine(seed int64) *RandomEngine {
+	return &RandomEngine{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (e *RandomEngine) Name() string {
+	return "Random"
+}
+
+func (e *RandomEngine) BestMove(b C4Board, p Player) Move {
+	moves := b.LegalMoves()
+	if len(moves) == 0 {
+		return 0
+	}
+	return moves[e.rng.Intn(len(moves))]
+}
+
+// GreedyEngine plays the move with the best Evaluate score right after it is made,
+// without looking at the opponent's reply
+type GreedyEngine struct{}
+
+func (GreedyEngine) Name() string {
+	return "Greedy"
+}
+
+func (e GreedyEngine) BestMove(b C4Board, p Player) Move {
+	move, _ := e.BestMoveScore(b, p)
+	return move
+}
+
+func (GreedyEngine) BestMoveScore(b C4Board, p Player) (Move, float32) {
+	var best Eval
+	haveBest := false
+	for _, move := range b.LegalMoves() {
+		if score := b.MakeMove(p, move).Evaluate(p.Piece); !haveBest || score > best.f {
+			best = Eval{m: move, f: score}
+			haveBest = true
+		}
+	}
+	return best.m, best.f
+}
+
+// SolverEngine plays perfectly with the Solver.
+// Solving is far too slow near the start of the game, so until MinStones stones are on the
+// board it asks Fallback for the move instead. It does the same when a solve takes longer than ThinkTime.
+type SolverEngine struct {
+	MinStones uint
+	ThinkTime time.Duration // 0 for no limit
+	Fallback  Engine
+	solver    *Solver
+}
+
+// NewSolverEngine returns a SolverEngine that takes over from fallback once minStones stones are on the board
+// and hands the move back to it when solving takes longer than thinkTime
+func NewSolverEngine(minStones uint, thinkTime time.Duration, fallback Engine) *SolverEngine {
+	return &SolverEngine{MinStones: minStones, ThinkTime: thinkTime, Fallback: fallback, solver: NewSolver()}
+}
+
+func (e *SolverEngine) Name() string {
+	return fmt.Sprintf("Perfect play (after %d stones, %s before)", e.MinStones, e.Fallback.Name())
+}
+
+func (e *SolverEngine) BestMove(b C4Board, p Player) Move {
+	move, _ := e.BestMoveScore(b, p)
+	return move
+}
+
+// BestMoveScore returns the solver score of the move once the solver has taken over,
+// see Solution for what it means. Before that, or if solving ran out of time,
+// it is the fallback's score, if it has one.
+func (e *SolverEngine) BestMoveScore(b C4Board, p Player) (Move, float32) {
+	if b.numRows*b.numCols-b.emptyCells() < e.MinStones {
+		return e.fallback(b, p)
+	}
+
+	ctx := context.Background()
+	if e.ThinkTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.ThinkTime)
+		defer cancel()
+	}
+	solution, err := e.solver.SolveContext(ctx, b, p)
+	if err != nil {
+		return e.fallback(b, p)
+	}
+	return solution.Move, float32(solution.Score)
+}
+
+// fallback asks Fallback for the move and its score
+func (e *SolverEngine) fallback(b C4Board, p Player) (Move, float32) {
+	if scoring, ok := e.Fallback.(ScoringEngine); ok {
+		return scoring.BestMoveScore(b, p)
+	}
+	return e.Fallback.BestMove(b, p), 0
+}
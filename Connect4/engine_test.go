@@ -0,0 +1,125 @@
+package connect4
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// countingEngine plays the lowest legal column and counts how often it was asked
+type countingEngine struct {
+	calls int
+}
+
+func (e *countingEngine) Name() string {
+	return "Counting"
+}
+
+func (e *countingEngine) BestMove(b C4Board, p Player) Move {
+	e.calls++
+	return b.LegalMoves()[0]
+}
+
+func TestRandomEngineSeed(t *testing.T) {
+	play := func(seed int64) []Move {
+		e := NewRandomEngine(seed)
+		b, p := NewBoard(), Player{Piece: PlayerIcon}
+		var moves []Move
+		for !b.IsGameOver() {
+			move := e.BestMove(b, p)
+			if !b.determineIfLegalMove(move) {
+				t.Fatalf("seed %d: illegal move %v after %v", seed, move, moves)
+			}
+			moves = append(moves, move)
+			b = b.MakeMove(p, move)
+			p = p.Opponent()
+		}
+		return moves
+	}
+
+	first, second := play(7), play(7)
+	if !slices.Equal(first, second) {
+		t.Errorf("seed 7 played %v then %v", first, second)
+	}
+	if other := play(8); slices.Equal(other, first) {
+		t.Errorf("seeds 7 and 8 both played %v", first)
+	}
+}
+
+func TestGreedyEngineWins(t *testing.T) {
+	for _, tc := range []struct {
+		moves string
+		win   Move
+	}{
+		{"112233", 3},
+		{"1727376", 6}, // the second player wins on top of the last column
+		{"443322", 4},  // wins at either end of the row
+	} {
+		b, p := playMoves(t, tc.moves)
+		move, score := GreedyEngine{}.BestMoveScore(b, p)
+		if move != tc.win || !b.MakeMove(p, move).IsWin() {
+			t.Errorf("%q: GreedyEngine played %v with score %v, want the win %v", tc.moves, move, score, tc.win)
+		}
+	}
+}
+
+func TestSolverEngineFallback(t *testing.T) {
+	fallback := &countingEngine{}
+	e := NewSolverEngine(14, 0, fallback)
+
+	b, p := playMoves(t, "4455")
+	if move, score := e.BestMoveScore(b, p); move != 0 || score != 0 || fallback.calls != 1 {
+		t.Errorf("4 stones: move %v score %v with %d fallback calls, want the fallback's move 1", move, score, fallback.calls)
+	}
+
+	b, p = playMoves(t, "2634426427477344")
+	want, err := Solve(b, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move, score := e.BestMoveScore(b, p); move != want.Move || score != float32(want.Score) || fallback.calls != 1 {
+		t.Errorf("16 stones: move %v score %v with %d fallback calls, want the solver's move %v score %d",
+			move, score, fallback.calls, want.Move, want.Score)
+	}
+
+	// a board the solver can't play goes to the fallback however full it is
+	popOut := playPopOut(t, 0, 1, 2, 3, 4, 5, 6, 6, 5, 4, 3, 2, 1, 0)
+	if move := e.BestMove(popOut, Player{Piece: PlayerIcon}); move != 0 || fallback.calls != 2 {
+		t.Errorf("PopOut: move %v with %d fallback calls, want the fallback's move 1", move, fallback.calls)
+	}
+}
+
+func TestSolverEngineThinkTime(t *testing.T) {
+	fallback := &countingEngine{}
+	e := NewSolverEngine(0, time.Millisecond, fallback)
+
+	// solving the second move of the game takes far longer than a millisecond
+	b, p := playMoves(t, "4")
+	start := time.Now()
+	if move := e.BestMove(b, p); move != 0 || fallback.calls != 1 {
+		t.Errorf("move %v with %d fallback calls, want the fallback's move 1", move, fallback.calls)
+	}
+	if elapsed := time.Since(start); elapsed > cancelSlack {
+		t.Errorf("gave up solving after %v", elapsed)
+	}
+}
+
+func TestTimedEngineThinkTime(t *testing.T) {
+	b, p := playMoves(t, "4")
+	e := NewTimedEngine(50*time.Millisecond, 0)
+	start := time.Now()
+	result := e.Search(b, p)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > 50*time.Millisecond+cancelSlack {
+		t.Errorf("thinking for 50ms took %v", elapsed)
+	}
+	if !b.determineIfLegalMove(result.Move) {
+		t.Errorf("illegal move %v", result.Move)
+	}
+
+	// reaching MaxDepth ends the search well before the time is up
+	e = NewTimedEngine(time.Minute, 2)
+	start = time.Now()
+	if result := e.Search(b, p); result.Depth != 2 || time.Since(start) > cancelSlack {
+		t.Errorf("depth 2 search reached depth %d in %v", result.Depth, time.Since(start))
+	}
+}
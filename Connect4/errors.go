@@ -0,0 +1,62 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when a move can't be made.
+// Errors from TryMove wrap one of these in a *MoveError, check for them with errors.Is.
+var (
+	ErrColumnOutOfRange = errors.New("connect4: column out of range")
+	ErrColumnFull       = errors.New("connect4: column is full")
+	ErrGameOver         = errors.New("connect4: the game is already over")
+	ErrInvalidPiece     = errors.New("connect4: invalid piece")
+	ErrCannotPop        = errors.New("connect4: no piece of yours to pop in that column")
+)
+
+// ErrNotToMove is returned when solving for the player who isn't to move
+var ErrNotToMove = errors.New("connect4: not that player's move")
+
+// Errors about the size or rules of a board
+var (
+	ErrInvalidSize        = errors.New("connect4: invalid board size")
+	ErrUnsupportedSize    = errors.New("connect4: board size not supported")
+	ErrUnsupportedVariant = errors.New("connect4: game variant not supported")
+)
+
+// Errors from reading positions written down in notation
+var (
+	ErrInvalidNotation = errors.New("connect4: invalid notation")
+	ErrInvalidPosition = errors.New("connect4: impossible position")
+)
+
+// Errors from taking back and replaying moves in a Session
+var (
+	ErrNothingToUndo = errors.New("connect4: not enough moves to undo")
+	ErrNothingToRedo = errors.New("connect4: not enough moves to redo")
+)
+
+// ErrInvalidSave is returned for a save file that doesn't hold a game that can be resumed
+var ErrInvalidSave = errors.New("connect4: invalid saved game")
+
+// ErrInvalidRecord is returned for text that isn't a game record
+var ErrInvalidRecord = errors.New("connect4: invalid game record")
+
+// ErrInvalidTournament is returned for a tournament that can't be played, such as one with a single entrant
+var ErrInvalidTournament = errors.New("connect4: invalid tournament")
+
+// MoveError is the error for a move that was refused, saying which move and why
+type MoveError struct {
+	Col   Move
+	Piece Piece
+	Err   error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("move %v by %q: %v", e.Col, e.Piece.String(), e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
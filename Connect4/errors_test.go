@@ -0,0 +1,57 @@
+package connect4
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTryMoveErrors(t *testing.T) {
+	popOut := playPopOut(t, 3, 2)
+	full, _ := playMoves(t, "444444")
+	won, _ := playMoves(t, "1212121")
+	player, cpu := Player{Piece: PlayerIcon}, Player{Piece: CpuIcon}
+
+	tests := []struct {
+		name  string
+		board C4Board
+		p     Player
+		col   Move
+		want  error
+	}{
+		{"no piece", NewBoard(), Player{}, 3, ErrInvalidPiece},
+		{"past the last column", NewBoard(), player, 7, ErrColumnOutOfRange},
+		{"pop past the last column", popOut, player, PopMove(7), ErrColumnOutOfRange},
+		{"after the win", won, cpu, 3, ErrGameOver},
+		{"pop without PopOut", full, player, PopMove(3), ErrCannotPop},
+		{"pop the other piece", popOut, player, PopMove(2), ErrCannotPop},
+		{"pop an empty column", popOut, player, PopMove(0), ErrCannotPop},
+		{"full column", full, player, 3, ErrColumnFull},
+	}
+	for _, tt := range tests {
+		b, err := tt.board.TryMove(tt.p, tt.col)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+			continue
+		}
+		if b.String() != tt.board.String() {
+			t.Errorf("%s: the refused move changed the board\n%s", tt.name, b)
+		}
+
+		var moveErr *MoveError
+		if !errors.As(err, &moveErr) {
+			t.Errorf("%s: error %T is not a *MoveError", tt.name, err)
+			continue
+		}
+		if moveErr.Col != tt.col || moveErr.Piece != tt.p.Piece || moveErr.Err != tt.want {
+			t.Errorf("%s: MoveError %+v, want column %v piece %v err %v", tt.name, *moveErr, tt.col, tt.p.Piece, tt.want)
+		}
+		if !strings.Contains(err.Error(), tt.want.Error()) {
+			t.Errorf("%s: message %q doesn't say %q", tt.name, err, tt.want)
+		}
+	}
+
+	if b, err := popOut.TryMove(player, PopMove(3)); err != nil || b.String() == popOut.String() {
+		t.Errorf("legal pop: error = %v", err)
+	}
+}
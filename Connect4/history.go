@@ -0,0 +1,104 @@
+package connect4
+
+import (
+	"fmt"
+	"time"
+)
+
+// ------------------------------------------------------
+// Move history, undo and redo
+// ------------------------------------------------------
+// A board has no memory of how it got there, the session records every move so moves can be
+// taken back and played again. The board is never patched up by hand, it is always rebuilt
+// by replaying the history from the starting board so it ends up exactly as if the moves
+// had just been played (hash and PopOut repetitions included).
+
+// MoveRecord is one move of a game
+type MoveRecord struct {
+	Player Player
+	Col    Move
+	Time   time.Time // when the move was played
+}
+
+// BoardFromHistory replays history on start and returns the board it leads to.
+// Every move is checked with TryMove, the first illegal one stops the replay and its error is returned.
+func BoardFromHistory(start C4Board, history []MoveRecord) (C4Board, error) {
+	board := start
+	for i, record := range history {
+		next, err := board.TryMove(record.Player, record.Col)
+		if err != nil {
+			return start, fmt.Errorf("move %d of the history: %w", i+1, err)
+		}
+		board = next
+	}
+	return board, nil
+}
+
+// History returns the moves played so far, oldest first
+func (s *Session) History() []MoveRecord {
+	return append([]MoveRecord(nil), s.history...)
+}
+
+// RedoMoves returns the moves Redo would play, the next one first
+func (s *Session) RedoMoves() []MoveRecord {
+	moves := make([]MoveRecord, 0, len(s.undone))
+	for i := len(s.undone) - 1; i >= 0; i-- {
+		moves = append(moves, s.undone[i])
+	}
+	return moves
+}
+
+// Undo takes back the last plies moves, they can be played again with Redo until a new move is played.
+// Returns ErrNothingToUndo and changes nothing if fewer than plies moves have been played.
+// Players' TurnCount counters are not wound back.
+func (s *Session) Undo(plies int) error {
+	if plies > len(s.history) {
+		return fmt.Errorf("%w: %d moves asked for, %d played", ErrNothingToUndo, plies, len(s.history))
+	}
+	if plies <= 0 {
+		return nil
+	}
+
+	keep := len(s.history) - plies
+	board, err := BoardFromHistory(s.start, s.history[:keep])
+	if err != nil {
+		return err
+	}
+
+	undone := s.history[keep:]
+	for i := len(undone) - 1; i >= 0; i-- {
+		s.undone = append(s.undone, undone[i])
+	}
+	s.history = s.history[:keep:keep]
+	s.board = board
+	s.next = keep % 2
+
+	s.emit(UndoEvent{Moves: append([]MoveRecord(nil), undone...), Board: board})
+	return nil
+}
+
+// Redo plays the last plies moves taken back by Undo again, with the times they were first played at.
+// Returns ErrNothingToRedo and changes nothing if fewer than plies moves are waiting to be redone.
+func (s *Session) Redo(plies int) error {
+	if plies > len(s.undone) {
+		return fmt.Errorf("%w: %d moves asked for, %d undone", ErrNothingToRedo, plies, len(s.undone))
+	}
+	if plies <= 0 {
+		return nil
+	}
+
+	redone := s.RedoMoves()[:plies]
+	board, err := BoardFromHistory(s.board, redone)
+	if err != nil {
+		return err
+	}
+
+	s.undone = s.undone[:len(s.undone)-plies]
+	s.history = append(s.history, redone...)
+	s.board = board
+	s.next = len(s.history) % 2
+
+	s.emit(RedoEvent{Moves: redone, Board: board})
+	s.emitResult(board)
+	return nil
+}
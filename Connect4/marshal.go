@@ -0,0 +1,333 @@
+package connect4
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ------------------------------------------------------
+// JSON and text encoding
+// ------------------------------------------------------
+// The board, pieces, moves, players and results can be sent or stored as JSON or text.
+// The schema only ever grows: fields are never renamed or reused, so older files keep loading.
+//
+//	Piece       "+", "*" or "" for an empty cell
+//	Move        the move sequence notation, "4" or "p4" (columns counted from 1)
+//	C4Board     {"cols": 7, "rows": 6, "winLength": 4, "popOut": false, "toMove": "+",
+//	             "cells": ["......."...]} with the rows from the top down, . for an empty cell.
+//	            As text it is the grid, see ParseGrid.
+//	Player      {"name": "Ann", "piece": "+", "isHuman": true, "engine": "Greedy"}
+//	GameResult  {"outcome": "win", "winner": "+", "line": [{"col": 0, "row": 0}...]}
+//	            As text "in progress", "draw" or "win" with the winner and the line, "win + 0,0 1,1 2,2 3,3".
+//
+// A board is checked when it is read, so a board with floating pieces or a piece count that
+// can't come about in a game is refused with ErrInvalidPosition. A PopOut board doesn't remember
+// the positions before it, so the repetitions that led to it aren't counted.
+
+// MarshalText encodes the piece as "+", "*" or "" for Empty
+func (piece Piece) MarshalText() ([]byte, error) {
+	switch piece {
+	case PlayerIcon, CpuIcon:
+		return []byte(piece.String()), nil
+	case Empty:
+		return []byte{}, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrInvalidPiece, uint(piece))
+}
+
+// UnmarshalText decodes a piece written by MarshalText
+func (piece *Piece) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "+":
+		*piece = PlayerIcon
+	case "*":
+		*piece = CpuIcon
+	case "":
+		*piece = Empty
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidPiece, text)
+	}
+	return nil
+}
+
+func (piece Piece) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(piece)
+}
+
+func (piece *Piece) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, piece.UnmarshalText)
+}
+
+// MarshalText encodes the move in the move sequence notation, "4" or "p4"
+func (m Move) MarshalText() ([]byte, error) {
+	if m.Col() >= MaxCols {
+		return nil, fmt.Errorf("%w: move %d", ErrColumnOutOfRange, uint(m))
+	}
+	return []byte(FormatMoves([]Move{m})), nil
+}
+
+// UnmarshalText decodes a single move in the move sequence notation
+func (m *Move) UnmarshalText(text []byte) error {
+	moves, err := ParseMoves(string(text))
+	if err != nil {
+		return err
+	}
+	if len(moves) != 1 {
+		return fmt.Errorf("%w: %q is not a single move", ErrInvalidNotation, text)
+	}
+	*m = moves[0]
+	return nil
+}
+
+func (m Move) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(m)
+}
+
+func (m *Move) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, m.UnmarshalText)
+}
+
+// boardJSON is the JSON schema of a C4Board
+type boardJSON struct {
+	Cols      uint     `json:"cols"`
+	Rows      uint     `json:"rows"`
+	WinLength uint     `json:"winLength"`
+	PopOut    bool     `json:"popOut"`
+	ToMove    Piece    `json:"toMove"`
+	Cells     []string `json:"cells"` // top row first
+}
+
+// MarshalText encodes the board as its Grid
+func (board C4Board) MarshalText() ([]byte, error) {
+	if board.numCols == 0 {
+		return nil, fmt.Errorf("%w: the zero C4Board has no size, use NewBoard", ErrInvalidSize)
+	}
+	return []byte(board.Grid()), nil
+}
+
+// UnmarshalText decodes a grid, returning the errors of ParseGrid
+func (board *C4Board) UnmarshalText(text []byte) error {
+	b, err := ParseGrid(string(text))
+	if err != nil {
+		return err
+	}
+	*board = b
+	return nil
+}
+
+func (board C4Board) MarshalJSON() ([]byte, error) {
+	if board.numCols == 0 {
+		return nil, fmt.Errorf("%w: the zero C4Board has no size, use NewBoard", ErrInvalidSize)
+	}
+
+	b := boardJSON{
+		Cols:      board.numCols,
+		Rows:      board.numRows,
+		WinLength: board.winLength,
+		PopOut:    board.popOut,
+		ToMove:    board.nextPiece(),
+	}
+	for row := int(board.numRows) - 1; row >= 0; row-- {
+		var sb strings.Builder
+		for col := 0; col < int(board.numCols); col++ {
+			if piece := board.position[col][row]; piece == Empty {
+				sb.WriteString(".")
+			} else {
+				sb.WriteString(piece.String())
+			}
+		}
+		b.Cells = append(b.Cells, sb.String())
+	}
+	return json.Marshal(b)
+}
+
+// UnmarshalJSON decodes a board, returning ErrInvalidNotation if the cells don't match the size,
+// ErrInvalidSize if the board is too small or too large and ErrInvalidPosition if the position
+// can't come up in a game
+func (board *C4Board) UnmarshalJSON(data []byte) error {
+	var b boardJSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	if b.ToMove == Empty {
+		return fmt.Errorf("%w: no piece to move", ErrInvalidNotation)
+	}
+	if uint(len(b.Cells)) != b.Rows {
+		return fmt.Errorf("%w: %d rows of cells on a board %d rows high", ErrInvalidNotation, len(b.Cells), b.Rows)
+	}
+
+	cells := make([][]Piece, len(b.Cells))
+	for i, rank := range b.Cells {
+		if uint(len(rank)) != b.Cols {
+			return fmt.Errorf("%w: row %q on a board %d columns wide", ErrInvalidNotation, rank, b.Cols)
+		}
+		for _, c := range rank {
+			var piece Piece
+			if c != '.' {
+				if err := piece.UnmarshalText([]byte(string(c))); err != nil {
+					return fmt.Errorf("%w: %q in row %q", ErrInvalidNotation, c, rank)
+				}
+			}
+			cells[i] = append(cells[i], piece)
+		}
+	}
+
+	winLength := b.WinLength
+	if winLength == 0 {
+		winLength = WinLength
+	}
+	decoded, err := boardFromCells(cells, b.ToMove, winLength, b.PopOut)
+	if err != nil {
+		return err
+	}
+	*board = decoded
+	return nil
+}
+
+// playerJSON is the JSON schema of a Player
+type playerJSON struct {
+	Name    string `json:"name"`
+	Piece   Piece  `json:"piece"`
+	IsHuman bool   `json:"isHuman"`
+	Engine  string `json:"engine,omitempty"`
+}
+
+// MarshalJSON encodes the player, the engine only by its Name and without the TurnCount counter
+func (p Player) MarshalJSON() ([]byte, error) {
+	pj := playerJSON{Name: p.Name, Piece: p.Piece, IsHuman: p.IsHuman}
+	if p.Engine != nil {
+		pj.Engine = p.Engine.Name()
+	}
+	return json.Marshal(pj)
+}
+
+// UnmarshalJSON decodes a player. The engine can't be made from its name, Engine and TurnCount are left nil.
+func (p *Player) UnmarshalJSON(data []byte) error {
+	var pj playerJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	*p = Player{Name: pj.Name, Piece: pj.Piece, IsHuman: pj.IsHuman}
+	return nil
+}
+
+// MarshalText encodes the outcome as "in progress", "win" or "draw"
+func (o Outcome) MarshalText() ([]byte, error) {
+	switch o {
+	case InProgress, Win, Draw:
+		return []byte(strings.ToLower(o.String())), nil
+	}
+	return nil, fmt.Errorf("%w: outcome %d", ErrInvalidNotation, uint8(o))
+}
+
+// UnmarshalText decodes an outcome written by MarshalText
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for _, outcome := range []Outcome{InProgress, Win, Draw} {
+		if string(text) == strings.ToLower(outcome.String()) {
+			*o = outcome
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not an outcome", ErrInvalidNotation, text)
+}
+
+// resultJSON is the JSON schema of a GameResult
+type resultJSON struct {
+	Outcome Outcome `json:"outcome"`
+	Winner  Piece   `json:"winner,omitempty"`
+	Line    []Cell  `json:"line,omitempty"`
+}
+
+// MarshalText encodes the result as "in progress", "draw" or "win + 0,0 1,1 2,2 3,3"
+// with the winner and the cells of the line
+func (r GameResult) MarshalText() ([]byte, error) {
+	outcome, err := r.Outcome.MarshalText()
+	if err != nil || r.Outcome != Win {
+		return outcome, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %v", outcome, r.Winner)
+	for _, cell := range r.Line {
+		fmt.Fprintf(&sb, " %d,%d", cell.Col, cell.Row)
+	}
+	return []byte(sb.String()), nil
+}
+
+// UnmarshalText decodes a result written by MarshalText
+func (r *GameResult) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	if len(fields) == 0 || fields[0] != "win" {
+		var result GameResult
+		if err := result.Outcome.UnmarshalText(text); err != nil {
+			return err
+		}
+		*r = result
+		return nil
+	}
+
+	result := GameResult{Outcome: Win}
+	if len(fields) < 2 {
+		return fmt.Errorf("%w: result %q has no winner", ErrInvalidNotation, text)
+	}
+	if err := result.Winner.UnmarshalText([]byte(fields[1])); err != nil {
+		return err
+	}
+	for _, field := range fields[2:] {
+		var cell Cell
+		if _, err := fmt.Sscanf(field, "%d,%d", &cell.Col, &cell.Row); err != nil {
+			return fmt.Errorf("%w: %q is not a cell", ErrInvalidNotation, field)
+		}
+		result.Line = append(result.Line, cell)
+	}
+	if err := result.check(); err != nil {
+		return err
+	}
+	*r = result
+	return nil
+}
+
+func (r GameResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultJSON(r))
+}
+
+// UnmarshalJSON decodes a result, returning ErrInvalidNotation if it has a winner without a win or the other way round
+func (r *GameResult) UnmarshalJSON(data []byte) error {
+	var rj resultJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+	result := GameResult(rj)
+	if err := result.check(); err != nil {
+		return err
+	}
+	*r = result
+	return nil
+}
+
+// check returns ErrInvalidNotation unless only a win has a winner and a line
+func (r GameResult) check() error {
+	if (r.Outcome == Win) != (r.Winner != Empty) || (r.Outcome == Win) != (len(r.Line) > 0) {
+		return fmt.Errorf("%w: %v with winner %q and %d cells in the line", ErrInvalidNotation, r.Outcome, r.Winner, len(r.Line))
+	}
+	return nil
+}
+
+// marshalTextJSON encodes v's text as a JSON string
+func marshalTextJSON(v interface{ MarshalText() ([]byte, error) }) ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// unmarshalTextJSON decodes a JSON string with unmarshalText
+func unmarshalTextJSON(data []byte, unmarshalText func(text []byte) error) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return unmarshalText([]byte(text))
+}
@@ -0,0 +1,166 @@
+package connect4
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBoardJSON(t *testing.T) {
+	b, err := NewBoard().PlayMoves("4453")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"cols":7,"rows":6,"winLength":4,"popOut":false,"toMove":"+",` +
+		`"cells":[".......",".......",".......",".......","...*...","..*++.."]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	var decoded C4Board
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Grid() != b.Grid() || decoded.Hash() != b.Hash() {
+		t.Errorf("decoded %q, want %q", decoded.Grid(), b.Grid())
+	}
+
+	if _, err := json.Marshal(C4Board{}); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("json.Marshal of the zero board: error = %v, want ErrInvalidSize", err)
+	}
+}
+
+func TestBoardJSONRejectsImpossibleBoards(t *testing.T) {
+	const empty = `".......",".......",".......",".......",`
+	tests := []struct {
+		name string
+		json string
+		err  error
+	}{
+		{"floating piece", `{"cols":7,"rows":6,"toMove":"*","cells":[` + empty + `"...+...","......."]}`, ErrInvalidPosition},
+		{"wrong piece count", `{"cols":7,"rows":6,"toMove":"+","cells":[` + empty + `".......","..++..."]}`, ErrInvalidPosition},
+		{"row too short", `{"cols":7,"rows":6,"toMove":"+","cells":[` + empty + `".......","......"]}`, ErrInvalidNotation},
+		{"missing row", `{"cols":7,"rows":6,"toMove":"+","cells":[` + empty + `"......."]}`, ErrInvalidNotation},
+		{"bad cell", `{"cols":7,"rows":6,"toMove":"+","cells":[` + empty + `".......","...x..."]}`, ErrInvalidNotation},
+		{"nobody to move", `{"cols":7,"rows":6,"cells":[` + empty + `".......","......."]}`, ErrInvalidNotation},
+		{"bad piece to move", `{"cols":7,"rows":6,"toMove":"x","cells":[` + empty + `".......","......."]}`, ErrInvalidPiece},
+		{"too small", `{"cols":3,"rows":1,"toMove":"+","cells":["..."]}`, ErrInvalidSize},
+	}
+	for _, tt := range tests {
+		var b C4Board
+		if err := json.Unmarshal([]byte(tt.json), &b); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestBoardText(t *testing.T) {
+	b, err := NewPopOutBoard(8, 7, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err = b.PlayMoves("88p8"); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := b.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded C4Board
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Grid() != b.Grid() || !decoded.IsPopOut() || decoded.WinLength() != 5 {
+		t.Errorf("decoded %q, want %q", decoded.Grid(), b.Grid())
+	}
+	if want := "8/8/8/8/8/8/7* * 5 popout"; string(text) != want {
+		t.Errorf("MarshalText() = %q, want %q", text, want)
+	}
+}
+
+func TestPieceMoveAndPlayerJSON(t *testing.T) {
+	type value struct {
+		Pieces []Piece
+		Moves  []Move
+		Player Player
+	}
+	v := value{
+		Pieces: []Piece{PlayerIcon, CpuIcon, Empty},
+		Moves:  []Move{0, 9, PopMove(3)},
+		Player: Player{Name: "Bot", Piece: CpuIcon, Engine: GreedyEngine{}, TurnCount: incrementer()},
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Pieces":["+","*",""],"Moves":["1","10","p4"],"Player":{"name":"Bot","piece":"*","isHuman":false,"engine":"Greedy"}}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	var decoded value
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Pieces, v.Pieces) || !reflect.DeepEqual(decoded.Moves, v.Moves) ||
+		decoded.Player.Name != "Bot" || decoded.Player.Piece != CpuIcon || decoded.Player.Engine != nil {
+		t.Errorf("decoded %+v", decoded)
+	}
+
+	if _, err := json.Marshal(Piece(3)); !errors.Is(err, ErrInvalidPiece) {
+		t.Errorf("json.Marshal(Piece(3)) error = %v, want ErrInvalidPiece", err)
+	}
+	if _, err := json.Marshal(Move(MaxCols)); !errors.Is(err, ErrColumnOutOfRange) {
+		t.Errorf("json.Marshal(Move(MaxCols)) error = %v, want ErrColumnOutOfRange", err)
+	}
+	var m Move
+	for _, bad := range []string{`"0"`, `"44"`, `3`} {
+		if err := json.Unmarshal([]byte(bad), &m); err == nil {
+			t.Errorf("json.Unmarshal(%s) into a Move succeeded", bad)
+		}
+	}
+}
+
+func TestGameResultEncoding(t *testing.T) {
+	b, err := NewBoard().PlayMoves("1212121")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []GameResult{b.Result(), {Outcome: Draw}, {Outcome: InProgress}}
+
+	for _, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromJSON GameResult
+		if err := json.Unmarshal(data, &fromJSON); err != nil || !reflect.DeepEqual(fromJSON, result) {
+			t.Errorf("JSON %s decoded to %v, %v, want %v", data, fromJSON, err, result)
+		}
+
+		text, err := result.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromText GameResult
+		if err := fromText.UnmarshalText(text); err != nil || !reflect.DeepEqual(fromText, result) {
+			t.Errorf("text %q decoded to %v, %v, want %v", text, fromText, err, result)
+		}
+	}
+
+	if text, _ := results[0].MarshalText(); string(text) != "win + 0,0 0,1 0,2 0,3" {
+		t.Errorf("MarshalText() = %q", text)
+	}
+	for _, bad := range []string{`{"outcome":"win"}`, `{"outcome":"draw","winner":"+"}`, `{"outcome":"lost"}`} {
+		var r GameResult
+		if err := json.Unmarshal([]byte(bad), &r); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("json.Unmarshal(%s) error = %v, want ErrInvalidNotation", bad, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package connect4
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+// ------------------------------------------------------
+// Monte Carlo tree search
+// ------------------------------------------------------
+// MCTS plays many random games (playouts) from the position and grows a tree towards the moves
+// whose playouts go best, picking the child to explore with UCT (upper confidence bounds for trees).
+// It needs no evaluation function, the score of a move is simply how often its playouts were won.
+
+// Exploration constant for UCT, higher values try the less visited moves more often
+const uctExploration = math.Sqrt2
+
+// Playouts per move when an MCTSEngine has neither an iteration nor a time budget
+const DefaultMCTSIterations = 20000
+
+// MCTSEngine chooses moves with Monte Carlo tree search.
+// Every worker goroutine grows its own tree from the position and the visit counts of the root
+// moves are added up at the end, the most visited move is played.
+// With an iteration budget and a fixed seed the engine plays the same moves every time.
+// The zero value plays DefaultMCTSIterations playouts per move seeded from the clock.
+// Playouts run on a BitBoard, on boards too large for one (see FitsBitBoard) and PopOut boards
+// they run on the slower C4Board.
+type MCTSEngine struct {
+	Iterations int           // playouts per move across all workers, 0 for no limit
+	ThinkTime  time.Duration // time per move, 0 for no limit
+	Workers    int           // goroutines running playouts, 0 for one per CPU
+	rng        *rand.Rand    // seeds the workers, created on the first move if nil
+}
+
+// NewMCTSEngine returns an MCTSEngine with the given budgets whose random playouts are seeded with seed
+func NewMCTSEngine(iterations int, thinkTime time.Duration, seed int64) *MCTSEngine {
+	return &MCTSEngine{Iterations: iterations, ThinkTime: thinkTime, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (e *MCTSEngine) Name() string {
+	switch {
+	case e.Iterations > 0 && e.ThinkTime > 0:
+		return fmt.Sprintf("Monte Carlo tree search (%d playouts, %v)", e.Iterations, e.ThinkTime)
+	case e.ThinkTime > 0:
+		return fmt.Sprintf("Monte Carlo tree search (%v)", e.ThinkTime)
+	default:
+		return fmt.Sprintf("Monte Carlo tree search (%d playouts)", e.iterations())
+	}
+}
+
+func (e *MCTSEngine) BestMove(b C4Board, p Player) Move {
+	move, _ := e.BestMoveScore(b, p)
+	return move
+}
+
+// BestMoveScore returns the most visited move and the fraction of its playouts p won,
+// draws counting as half a win. If no playout finished, such as with a ThinkTime too short
+// for one, it plays as noPlayoutMove does.
+func (e *MCTSEngine) BestMoveScore(b C4Board, p Player) (Move, float32) {
+	workers := e.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	iterations := e.iterations()
+	if iterations > 0 && iterations < workers {
+		workers = iterations
+	}
+
+	var deadline time.Time
+	if e.ThinkTime > 0 {
+		deadline = time.Now().Add(e.ThinkTime)
+	}
+
+	if e.rng == nil {
+		e.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	// Playouts run on a bitboard unless the board is too big for one or plays PopOut
+	bitRoot, bitErr := ToBitBoard(b)
+	results := make(chan []mctsStats, workers)
+
+	for w := 0; w < workers; w++ {
+		// Seeds are drawn up front so the outcome doesn't depend on goroutine scheduling
+		budget := 0
+		if iterations > 0 {
+			budget = iterations / workers
+			if w < iterations%workers {
+				budget++
+			}
+		}
+		go func(seed int64, budget int) {
+			rng := rand.New(rand.NewSource(seed))
+			if bitErr == nil {
+				results <- runMCTS(bitRoot, p.Piece, budget, deadline, rng)
+			} else {
+				results <- runMCTS(b, p.Piece, budget, deadline, rng)
+			}
+		}(e.rng.Int63(), budget)
+	}
+
+	totals := make(map[Move]mctsStats)
+	for w := 0; w < workers; w++ {
+		for _, stats := range <-results {
+			total := totals[stats.move]
+			total.move = stats.move
+			total.visits += stats.visits
+			total.wins += stats.wins
+			totals[stats.move] = total
+		}
+	}
+	close(results)
+
+	var best mctsStats
+	for _, move := range b.LegalMoves() {
+		if stats := totals[move]; stats.visits > best.visits {
+			best = stats
+			best.move = move
+		}
+	}
+	if best.visits == 0 {
+		return noPlayoutMove(b, p)
+	}
+	return best.move, float32(best.wins / float64(best.visits))
+}
+
+// noPlayoutMove is the move played without any playouts to go on: a move that wins on the spot,
+// scoring 1, or else the most central move, scoring 0.5 as nothing is known about it.
+// A board with no legal moves gets move 0 with a score of 0.
+func noPlayoutMove(b C4Board, p Player) (Move, float32) {
+	moves := b.OrderedMoves()
+	if len(moves) == 0 {
+		return 0, 0
+	}
+	for _, move := range moves {
+		if result := b.MakeMove(p, move).Result(); result.Outcome == Win && result.Winner == p.Piece {
+			return move, 1
+		}
+	}
+	return moves[0], 0.5
+}
+
+// iterations is the playout budget, falling back to the default when no budget is set
+func (e *MCTSEngine) iterations() int {
+	if e.Iterations == 0 && e.ThinkTime == 0 {
+		return DefaultMCTSIterations
+	}
+	return e.Iterations
+}
+
+// mctsStats is what a worker found out about one root move
+type mctsStats struct {
+	move   Move
+	visits int
+	wins   float64 // from the point of view of the player making the root move
+}
+
+// mctsBoard is what the tree search needs from a board, both BitBoard and C4Board have it.
+// BitBoard is far faster, C4Board is only used for boards a bitboard can't hold.
+type mctsBoard[B any] interface {
+	MakeMove(p Player, col Move) B
+	LegalMoves() []Move
+	appendMoves(moves []Move) []Move
+	winnerAfter(move Move, mover Piece) Piece
+	drawn() bool
+}
+
+// Random playouts stop as a draw after this many moves, only PopOut games can go on that long
+const maxPlayoutMoves = 4 * MaxCols * MaxRows
+
+// mctsNode is a position in the search tree, reached by playing move
+type mctsNode[B mctsBoard[B]] struct {
+	board    B
+	move     Move
+	mover    Piece // who played move
+	parent   *mctsNode[B]
+	children []*mctsNode[B]
+	untried  []Move
+	visits   int
+	wins     float64 // playouts won by mover, draws count half
+	winner   Piece   // who won with move, Empty if nobody
+	terminal bool
+}
+
+func newMCTSNode[B mctsBoard[B]](board B, move Move, mover Piece, parent *mctsNode[B]) *mctsNode[B] {
+	node := &mctsNode[B]{board: board, move: move, mover: mover, parent: parent}
+	node.winner = board.winnerAfter(move, mover)
+	node.terminal = node.winner != Empty || board.drawn()
+	if !node.terminal {
+		node.untried = board.LegalMoves()
+	}
+	return node
+}
+
+// uct is the score used to pick which child to go down, balancing
+// how well a child has done against how little it has been tried
+func (node *mctsNode[B]) uct(parentVisits int) float64 {
+	return node.wins/float64(node.visits) +
+		uctExploration*math.Sqrt(math.Log(float64(parentVisits))/float64(node.visits))
+}
+
+// runMCTS grows a tree from board with toMove to play, for budget playouts (0 for no limit)
+// or until deadline (zero for none), and returns the stats of the root moves
+func runMCTS[B mctsBoard[B]](board B, toMove Piece, budget int, deadline time.Time, rng *rand.Rand) []mctsStats {
+	// The root is "played" by the opponent so its children are toMove's moves
+	root := &mctsNode[B]{board: board, mover: toMove.opposite(), untried: board.LegalMoves()}
+
+	if budget == 0 && deadline.IsZero() {
+		return nil // no budget at all, nothing to do
+	}
+
+	for i := 0; budget == 0 || i < budget; i++ {
+		if !deadline.IsZero() && i%64 == 0 && time.Now().After(deadline) {
+			break
+		}
+
+		// Selection - walk down fully expanded nodes
+		node := root
+		for len(node.untried) == 0 && len(node.children) > 0 {
+			best := node.children[0]
+			for _, child := range node.children[1:] {
+				if child.uct(node.visits) > best.uct(node.visits) {
+					best = child
+				}
+			}
+			node = best
+		}
+
+		// Expansion - add one untried move
+		if !node.terminal && len(node.untried) > 0 {
+			pick := rng.Intn(len(node.untried))
+			move := node.untried[pick]
+			node.untried = append(node.untried[:pick], node.untried[pick+1:]...)
+
+			mover := node.mover.opposite()
+			child := newMCTSNode(node.board.MakeMove(Player{Piece: mover}, move), move, mover, node)
+			node.children = append(node.children, child)
+			node = child
+		}
+
+		// Simulation - play randomly to the end
+		winner := randomPlayout(node, rng)
+
+		// Backpropagation
+		for n := node; n != nil; n = n.parent {
+			n.visits++
+			if winner == n.mover {
+				n.wins++
+			} else if winner == Empty {
+				n.wins += 0.5
+			}
+		}
+	}
+
+	stats := make([]mctsStats, 0, len(root.children))
+	for _, child := range root.children {
+		stats = append(stats, mctsStats{move: child.move, visits: child.visits, wins: child.wins})
+	}
+	return stats
+}
+
+// randomPlayout plays random moves from node until the game ends and returns the winner, Empty for a draw
+func randomPlayout[B mctsBoard[B]](node *mctsNode[B], rng *rand.Rand) Piece {
+	if node.terminal {
+		return node.winner
+	}
+
+	board := node.board
+	mover := node.mover
+	var buf [2 * MaxCols]Move
+	for i := 0; i < maxPlayoutMoves; i++ {
+		moves := board.appendMoves(buf[:0])
+		if len(moves) == 0 {
+			return Empty
+		}
+
+		mover = mover.opposite()
+		move := moves[rng.Intn(len(moves))]
+		board = board.MakeMove(Player{Piece: mover}, move)
+		if winner := board.winnerAfter(move, mover); winner != Empty {
+			return winner
+		}
+	}
+	return Empty
+}
@@ -0,0 +1,69 @@
+package connect4
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMCTSSeed(t *testing.T) {
+	for _, moves := range []string{"", "4453", "2364171236641"} {
+		b, p := playMoves(t, moves)
+		first := NewMCTSEngine(3000, 0, 42)
+		first.Workers = 4
+		second := NewMCTSEngine(3000, 0, 42)
+		second.Workers = 4
+
+		move, score := first.BestMoveScore(b, p)
+		again, againScore := second.BestMoveScore(b, p)
+		if move != again || score != againScore {
+			t.Errorf("%q: seed 42 played %v (%v) then %v (%v)", moves, move, score, again, againScore)
+		}
+	}
+}
+
+func TestMCTSFindsWin(t *testing.T) {
+	for _, tc := range []struct {
+		moves string
+		win   Move
+	}{
+		{"112233", 3},
+		{"1727376", 6},
+	} {
+		b, p := playMoves(t, tc.moves)
+		move, score := NewMCTSEngine(5000, 0, 1).BestMoveScore(b, p)
+		if move != tc.win {
+			t.Errorf("%q: MCTS played %v with score %v, want %v", tc.moves, move, score, tc.win)
+		}
+	}
+}
+
+func TestMCTSWithoutPlayouts(t *testing.T) {
+	for _, tc := range []struct {
+		moves string
+		want  Move
+		score float32
+	}{
+		{"", 3, 0.5},
+		{"444444", 2, 0.5},
+		{"112233", 3, 1},
+		{"1727376", 6, 1},
+	} {
+		b, p := playMoves(t, tc.moves)
+		// the time is up before the first playout
+		e := NewMCTSEngine(0, time.Nanosecond, 1)
+		if move, score := e.BestMoveScore(b, p); move != tc.want || score != tc.score {
+			t.Errorf("%q: no playouts gave %v with score %v, want %v with %v", tc.moves, move, score, tc.want, tc.score)
+		}
+	}
+}
+
+func TestMCTSWithoutConstructor(t *testing.T) {
+	b, p := playMoves(t, "4453")
+	e := MCTSEngine{Iterations: 500} // no NewMCTSEngine, so no random source yet
+	if move := e.BestMove(b, p); !b.determineIfLegalMove(move) {
+		t.Errorf("an engine made without NewMCTSEngine played the illegal move %v", move)
+	}
+	if e.rng == nil {
+		t.Error("an engine made without NewMCTSEngine didn't keep its random source for the next move")
+	}
+}
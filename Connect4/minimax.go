@@ -1,18 +1,18 @@
 package connect4
 
 import (
+	"context"
 	"math"
+	"sync"
 )
 
 // Find the best possible outcome evaluation for originalPlayer
 // depth is initially the maximum depth
+// Maximizing levels play p's piece and minimizing levels play the opponent's piece,
+// every position is scored from p's point of view
 func MiniMax(b C4Board, maximizing bool, p Player, depth uint) float32 {
-	//if the game is over then we shouldn't be trying to go through the recursion
-	if b.IsGameOver() {
-		return 0
-	}
 	// Base case — terminal position or maximum depth reached
-	if b.IsWin() || b.IsDraw() || depth == 0 {
+	if b.IsGameOver() || depth == 0 {
 		return b.Evaluate(p.Piece)
 	}
 
@@ -28,8 +28,9 @@ func MiniMax(b C4Board, maximizing bool, p Player, depth uint) float32 {
 		return bestEval
 	} else { // minimizing
 		var worstEval float32 = math.MaxFloat32
+		opponent := p.Opponent()
 		for _, move := range b.LegalMoves() {
-			result := MiniMax(b.MakeMove(p, move), true, p, depth-1)
+			result := MiniMax(b.MakeMove(opponent, move), true, p, depth-1)
 			if result < worstEval {
 				worstEval = result
 			}
@@ -38,6 +39,152 @@ func MiniMax(b C4Board, maximizing bool, p Player, depth uint) float32 {
 	}
 }
 
+// AlphaBeta is MiniMax with alpha-beta pruning.
+// alpha is the score p is already assured of and beta is the score the opponent is already assured of,
+// any branch that falls outside of that window can't change the result and is cut off.
+// Moves are searched center-first (see OrderedMoves) which makes the cutoffs happen much earlier.
+// Called with the full window (-math.MaxFloat32, math.MaxFloat32) it returns exactly what MiniMax returns.
+func AlphaBeta(b C4Board, maximizing bool, p Player, depth uint, alpha, beta float32) float32 {
+	return AlphaBetaTT(b, maximizing, p, depth, alpha, beta, nil)
+}
+
+// AlphaBetaTT is AlphaBeta that looks positions up in tt before searching them
+// and stores the result afterwards. A nil tt searches without a table.
+// Only entries searched exactly as deep are used, so a table kept from deeper searches doesn't change the score.
+func AlphaBetaTT(b C4Board, maximizing bool, p Player, depth uint, alpha, beta float32, tt *TranspositionTable) float32 {
+	s := searcher{tt: tt, sameDepth: true}
+	return s.alphaBeta(b, maximizing, p, depth, alpha, beta)
+}
+
+// How many nodes a searcher visits between checks of its context
+const cancelCheckInterval = 256
+
+// searcher holds what a single search thread needs besides the position itself.
+// Goroutines searching the same position each get their own searcher and share the table.
+type searcher struct {
+	ctx     context.Context // nil never cancels
+	tt      *TranspositionTable
+	nodes   uint64
+	stopped bool // set once ctx is done, every score after that is meaningless
+
+	// only use table entries searched exactly as deep as asked, deeper ones can change the
+	// result of a fixed depth search, iterative deepening is better off with them
+	sameDepth bool
+}
+
+// stop reports whether the search has been cancelled, checking the context on the first node
+// and every so often after that, so a search started with a done context stops straight away
+func (s *searcher) stop() bool {
+	if !s.stopped && s.ctx != nil && s.nodes%cancelCheckInterval == 1 && s.ctx.Err() != nil {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+func (s *searcher) alphaBeta(b C4Board, maximizing bool, p Player, depth uint, alpha, beta float32) float32 {
+	s.nodes++
+	if s.stop() {
+		return 0
+	}
+
+	// Base case — terminal position or maximum depth reached
+	if b.IsGameOver() || depth == 0 {
+		return b.Evaluate(p.Piece)
+	}
+
+	mover := p
+	if !maximizing {
+		mover = p.Opponent()
+	}
+
+	// A stored result may answer the question outright or at least narrow the window
+	key := b.searchKey(mover.Piece, p.Piece)
+	if entry, ok := s.tt.probe(key, depth, s.sameDepth); ok {
+		switch entry.Bound {
+		case ExactBound:
+			return entry.Score
+		case LowerBound:
+			alpha = max(alpha, entry.Score)
+		case UpperBound:
+			beta = min(beta, entry.Score)
+		}
+		if alpha >= beta {
+			return entry.Score
+		}
+	}
+	searchedAlpha, searchedBeta := alpha, beta
+
+	var bestEval float32
+	var bestMove Move
+	if maximizing {
+		bestEval = -math.MaxFloat32
+		for _, move := range b.OrderedMoves() {
+			result := s.alphaBeta(b.MakeMove(mover, move), false, p, depth-1, alpha, beta)
+			if result > bestEval {
+				bestEval = result
+				bestMove = move
+			}
+			if bestEval > alpha {
+				alpha = bestEval
+			}
+			if alpha >= beta {
+				break // the opponent will never allow this line
+			}
+		}
+	} else { // minimizing
+		bestEval = math.MaxFloat32
+		for _, move := range b.OrderedMoves() {
+			result := s.alphaBeta(b.MakeMove(mover, move), true, p, depth-1, alpha, beta)
+			if result < bestEval {
+				bestEval = result
+				bestMove = move
+			}
+			if bestEval < beta {
+				beta = bestEval
+			}
+			if alpha >= beta {
+				break // p will never allow this line
+			}
+		}
+	}
+
+	// A cancelled search must not leave half-finished scores behind
+	if s.stopped {
+		return 0
+	}
+
+	// Scores outside of the window we searched with are only bounds
+	bound := ExactBound
+	if bestEval <= searchedAlpha {
+		bound = UpperBound
+	} else if bestEval >= searchedBeta {
+		bound = LowerBound
+	}
+	s.tt.Store(key, depth, bestEval, bound, bestMove)
+
+	return bestEval
+}
+
+// rootScore searches a single root move with alpha-beta, using best as the score to beat.
+// Ties are broken towards the lowest column the same way a plain MiniMax loop over LegalMoves does,
+// so moves to the left of the current best also have to be searched for an equal score.
+// Returns the score and whether the move replaces the current best.
+func (s *searcher) rootScore(b C4Board, p Player, move Move, depth uint, best Eval, haveBest bool) (float32, bool) {
+	if !haveBest {
+		score := s.alphaBeta(b.MakeMove(p, move), false, p, depth, -math.MaxFloat32, math.MaxFloat32)
+		return score, true
+	}
+
+	alpha := best.f
+	if move < best.m {
+		// anything scoring at least as much as best is an improvement
+		alpha = math.Nextafter32(best.f, -math.MaxFloat32)
+	}
+	score := s.alphaBeta(b.MakeMove(p, move), false, p, depth, alpha, math.MaxFloat32)
+
+	return score, score > alpha
+}
+
 // Eval represents a move evaluation
 type Eval struct {
 	m Move
@@ -47,49 +194,107 @@ type Eval struct {
 // ConcurrentFindBestMove finds the best possible move in
 // the current position looking up to depth ahead.
 // This version looks at each legal move from the starting position
-// concurrently (runs minimax on each legal move concurrently)
+// concurrently (runs alpha-beta on each legal move concurrently)
+// and picks the same move FindBestMove would
 func ConcurrentFindBestMove(b C4Board, p Player, depth uint) Move {
-	var bestMove Move
-	var bestScore float32 = -math.MaxFloat32
-	legalMoves := b.LegalMoves()
+	return ConcurrentFindBestMoveTT(b, p, depth, NewTranspositionTable(DefaultTableSize))
+}
 
-	scores := make(chan Eval, len(legalMoves))
+// ConcurrentFindBestMoveTT is ConcurrentFindBestMove with a caller supplied transposition table
+// that every goroutine shares, so the table can be reused between moves and its Stats inspected.
+// Only entries searched exactly depth deep are used so a reused table still gives the same move.
+func ConcurrentFindBestMoveTT(b C4Board, p Player, depth uint, tt *TranspositionTable) Move {
+	best, _, _ := concurrentSearch(context.Background(), b, p, depth, tt, true, b.LegalMoves())
+	return best.m
+}
+
+// concurrentSearch runs one goroutine per move in moves and picks the best of them,
+// it also returns how many nodes all of the goroutines searched. sameDepth is passed on to every searcher.
+// If ctx is cancelled every goroutine gives up, concurrentSearch waits for all of them
+// to return and reports false if any of them was cut short because its score is incomplete.
+// A search that finished before the cancellation is noticed still counts.
+func concurrentSearch(ctx context.Context, b C4Board, p Player, depth uint, tt *TranspositionTable, sameDepth bool, moves []Move) (Eval, uint64, bool) {
+	var best Eval
+	haveBest := false
+
+	// each goroutine sends its move's score, the nodes it searched and whether it was cut short
+	type rootResult struct {
+		eval    Eval
+		nodes   uint64
+		stopped bool
+	}
+	results := make(chan rootResult, len(moves))
+	var wg sync.WaitGroup
 
-	for _, move := range legalMoves {
+	for _, move := range moves {
+		wg.Add(1)
 		go func(move Move) {
+			defer wg.Done()
+			s := searcher{ctx: ctx, tt: tt, sameDepth: sameDepth}
 			var e Eval
 			e.m = move
-			e.f = MiniMax(b.MakeMove(p, move), false, b.turn, depth)
-			scores <- e
+			e.f = s.alphaBeta(b.MakeMove(p, move), false, p, depth, -math.MaxFloat32, math.MaxFloat32)
+			results <- rootResult{eval: e, nodes: s.nodes, stopped: s.stopped}
 		}(move)
 	}
+	wg.Wait()
+	close(results)
 
-	for i := 0; i < len(legalMoves); i++ {
-		eval := <-scores
-		//fmt.Printf("m: %d, f: %f\n", eval.m, eval.f)
-		if eval.f > bestScore {
-			bestScore = eval.f
-			bestMove = eval.m
+	var searched uint64
+	finished := true
+	for result := range results {
+		searched += result.nodes
+		if result.stopped {
+			finished = false
+			continue
 		}
+		//fmt.Printf("m: %d, f: %f\n", result.eval.m, result.eval.f)
+		// Results arrive in any order, ties go to the lowest column
+		eval := result.eval
+		if !haveBest || eval.f > best.f || (eval.f == best.f && eval.m < best.m) {
+			best = eval
+			haveBest = true
+		}
+	}
+	if !finished {
+		return Eval{}, searched, false
 	}
-	close(scores)
 
-	return bestMove
+	return best, searched, true
 }
 
 // FindBestMove finds the best possible move in the current position
 // looking up to depth ahead
 // The Function will find the best move on the provided board for the player p that is providedin paramters
+// Root moves are searched center-first with alpha-beta but ties still go to the lowest column
+// so the result is the same move a plain MiniMax search would pick
 func FindBestMove(b C4Board, p Player, depth uint) Move {
-	var bestMove Move
-	var bestScore float32 = -math.MaxFloat32
+	return FindBestMoveTT(b, p, depth, NewTranspositionTable(DefaultTableSize))
+}
 
-	for _, move := range b.LegalMoves() {
-		if score := MiniMax(b.MakeMove(p, move), false, b.turn, depth); score > bestScore {
-			bestMove = move
-			bestScore = score
+// FindBestMoveTT is FindBestMove with a caller supplied transposition table.
+// Only entries searched exactly depth deep are used so a reused table still gives the same move.
+func FindBestMoveTT(b C4Board, p Player, depth uint, tt *TranspositionTable) Move {
+	s := searcher{tt: tt, sameDepth: true}
+	best, _ := s.searchRoot(b, p, depth, b.OrderedMoves())
+	return best.m
+}
+
+// searchRoot searches the root moves in the order given and returns the best one with its score.
+// Reports false if the search was cancelled before it finished.
+func (s *searcher) searchRoot(b C4Board, p Player, depth uint, moves []Move) (Eval, bool) {
+	var best Eval
+	haveBest := false
+
+	for _, move := range moves {
+		if score, better := s.rootScore(b, p, move, depth, best, haveBest); better {
+			best = Eval{m: move, f: score}
+			haveBest = true
+		}
+		if s.stopped {
+			return Eval{}, false
 		}
 	}
 
-	return bestMove
+	return best, true
 }
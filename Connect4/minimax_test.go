@@ -0,0 +1,112 @@
+package connect4
+
+import (
+	"math"
+	"testing"
+)
+
+// playMoves plays a sequence of 1-based columns starting with PlayerIcon and
+// returns the board and the player to move next
+func playMoves(t *testing.T, moves string) (C4Board, Player) {
+	t.Helper()
+	b := NewBoard()
+	p := Player{Piece: PlayerIcon}
+	for _, c := range moves {
+		col := Move(c - '1')
+		if !b.determineIfLegalMove(col) {
+			t.Fatalf("illegal move %c in %q", c, moves)
+		}
+		b = b.MakeMove(p, col)
+		p = p.Opponent()
+	}
+	return b, p
+}
+
+// Positions searched by the alpha-beta tests, from the empty board to ones with a win or a loss on the board
+var searchPositions = []struct {
+	moves string
+	depth uint
+}{
+	{"", 4},
+	{"4", 4},
+	{"4453", 4},
+	{"44332", 3},  // the opponent threatens to win on either side
+	{"112233", 3}, // the first player wins on the spot
+	{"2364171236641", 4},
+	{"26344264274773", 4},
+}
+
+// miniMaxRoot is the plain MiniMax root loop the faster searches have to agree with:
+// every legal move is searched with MiniMax and the lowest column wins ties
+func miniMaxRoot(b C4Board, p Player, depth uint) (Move, float32) {
+	var best Eval
+	haveBest := false
+	for _, move := range b.LegalMoves() {
+		if score := MiniMax(b.MakeMove(p, move), false, p, depth); !haveBest || score > best.f {
+			best = Eval{m: move, f: score}
+			haveBest = true
+		}
+	}
+	return best.m, best.f
+}
+
+// miniMaxNodes counts the positions MiniMax visits searching b depth deep with mover to move
+func miniMaxNodes(b C4Board, mover Player, depth uint) uint64 {
+	if b.IsGameOver() || depth == 0 {
+		return 1
+	}
+	nodes := uint64(1)
+	for _, move := range b.LegalMoves() {
+		nodes += miniMaxNodes(b.MakeMove(mover, move), mover.Opponent(), depth-1)
+	}
+	return nodes
+}
+
+func TestAlphaBetaMatchesMiniMax(t *testing.T) {
+	for _, tc := range searchPositions {
+		b, p := playMoves(t, tc.moves)
+
+		want := MiniMax(b, true, p, tc.depth+1)
+		if got := AlphaBeta(b, true, p, tc.depth+1, -math.MaxFloat32, math.MaxFloat32); got != want {
+			t.Errorf("%q: AlphaBeta = %v, want MiniMax's %v", tc.moves, got, want)
+		}
+		for _, maximizing := range []bool{true, false} {
+			want := MiniMax(b, maximizing, p, tc.depth)
+			if got := AlphaBeta(b, maximizing, p, tc.depth, -math.MaxFloat32, math.MaxFloat32); got != want {
+				t.Errorf("%q maximizing %v: AlphaBeta = %v, want MiniMax's %v", tc.moves, maximizing, got, want)
+			}
+		}
+	}
+}
+
+func TestFindBestMoveMatchesMiniMax(t *testing.T) {
+	for _, tc := range searchPositions {
+		b, p := playMoves(t, tc.moves)
+
+		want, score := miniMaxRoot(b, p, tc.depth)
+		if got := FindBestMove(b, p, tc.depth); got != want {
+			t.Errorf("%q: FindBestMove = %v, want MiniMax's %v (score %v)", tc.moves, got, want, score)
+		}
+		if got := ConcurrentFindBestMove(b, p, tc.depth); got != want {
+			t.Errorf("%q: ConcurrentFindBestMove = %v, want MiniMax's %v (score %v)", tc.moves, got, want, score)
+		}
+	}
+}
+
+func TestAlphaBetaSearchesFewerNodes(t *testing.T) {
+	for _, tc := range searchPositions {
+		b, p := playMoves(t, tc.moves)
+
+		var plain uint64
+		for _, move := range b.LegalMoves() {
+			plain += miniMaxNodes(b.MakeMove(p, move), p.Opponent(), tc.depth)
+		}
+
+		// without a table so only the pruning is measured
+		s := searcher{}
+		s.searchRoot(b, p, tc.depth, b.OrderedMoves())
+		if s.nodes >= plain {
+			t.Errorf("%q: alpha-beta searched %d nodes, MiniMax %d", tc.moves, s.nodes, plain)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package connect4
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------
+// Position notation
+// ------------------------------------------------------
+// Positions can be written down two ways, to share them in bug reports or load them as test fixtures.
+//
+// A move sequence lists the columns played counting from 1, the way most Connect 4 tools do,
+// so "4453" is the first player in the center column, the second on top of it and so on.
+// A PopOut pop is the column with a p in front ("44p4"). Column 10 is written 10, there is no
+// column 0 so "4104" can only be 4, 10, 4. Spaces or commas between the moves are allowed.
+//
+// A grid lists the rows from the top down separated by slashes, with + and * for the pieces
+// and a number for a run of empty cells, followed by the piece to move, the win length and
+// "popout" for the PopOut variant:
+//
+//	7/7/7/7/3*3/3+3 + 4
+//
+// is the standard board after "44" with + to move.
+
+// ParseMoves reads a move sequence such as "4453" into moves, returning ErrInvalidNotation if it can't
+func ParseMoves(sequence string) ([]Move, error) {
+	sequence = strings.TrimSpace(sequence)
+	separated := strings.ContainsAny(sequence, " ,")
+
+	var tokens []string
+	if separated {
+		tokens = strings.FieldsFunc(sequence, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	} else {
+		for i := 0; i < len(sequence); i++ {
+			// a p belongs to the column after it and a 0 to the 1 before it
+			end := i + 1
+			if sequence[i] == 'p' && end < len(sequence) {
+				end++
+			}
+			if sequence[end-1] == '1' && end < len(sequence) && sequence[end] == '0' {
+				end++
+			}
+			tokens = append(tokens, sequence[i:end])
+			i = end - 1
+		}
+	}
+
+	moves := make([]Move, 0, len(tokens))
+	for _, token := range tokens {
+		pop := strings.HasPrefix(token, "p")
+		col, err := strconv.ParseUint(strings.TrimPrefix(token, "p"), 10, 8)
+		if err != nil || col < 1 || col > MaxCols {
+			return nil, fmt.Errorf("%w: %q in move sequence %q", ErrInvalidNotation, token, sequence)
+		}
+		if pop {
+			moves = append(moves, PopMove(uint(col-1)))
+		} else {
+			moves = append(moves, Move(col-1))
+		}
+	}
+	return moves, nil
+}
+
+// FormatMoves writes moves as a move sequence that ParseMoves reads back
+func FormatMoves(moves []Move) string {
+	var sb strings.Builder
+	for _, move := range moves {
+		if move.IsPop() {
+			sb.WriteString("p")
+		}
+		sb.WriteString(strconv.FormatUint(uint64(move.Col())+1, 10))
+	}
+	return sb.String()
+}
+
+// PlayMoves plays a move sequence on board, the first move by the piece to move (PlayerIcon on an empty board).
+// Returns ErrInvalidNotation if the sequence can't be read, or the *MoveError of the first illegal move.
+func (board C4Board) PlayMoves(sequence string) (C4Board, error) {
+	moves, err := ParseMoves(sequence)
+	if err != nil {
+		return board, err
+	}
+
+	b := board
+	for i, move := range moves {
+		if b, err = b.TryMove(Player{Piece: b.nextPiece()}, move); err != nil {
+			return board, fmt.Errorf("move %d of %q: %w", i+1, sequence, err)
+		}
+	}
+	return b, nil
+}
+
+// nextPiece is the piece to move, PlayerIcon moves first
+func (board C4Board) nextPiece() Piece {
+	if board.next == Empty {
+		return PlayerIcon
+	}
+	return board.next
+}
+
+// Grid writes the board as a grid, see ParseGrid
+func (board C4Board) Grid() string {
+	var sb strings.Builder
+	for row := int(board.numRows) - 1; row >= 0; row-- {
+		empty := 0
+		for col := 0; col < int(board.numCols); col++ {
+			piece := board.position[col][row]
+			if piece == Empty {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteString(piece.String())
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if row > 0 {
+			sb.WriteString("/")
+		}
+	}
+
+	fmt.Fprintf(&sb, " %v %d", board.nextPiece(), board.winLength)
+	if board.popOut {
+		sb.WriteString(" popout")
+	}
+	return sb.String()
+}
+
+// ParseGrid reads a board written as a grid such as "7/7/7/7/3*3/3+3 + 4".
+// The win length and "popout" can be left off for four in a row without PopOut.
+// Returns ErrInvalidNotation if the grid can't be read, ErrInvalidSize if the board is too small
+// or too large and ErrInvalidPosition if the position can't come up in a game.
+// A PopOut board doesn't know the positions before it so it can't spot repetitions of them.
+func ParseGrid(grid string) (C4Board, error) {
+	fields := strings.Fields(grid)
+	if len(fields) < 2 || len(fields) > 4 {
+		return C4Board{}, fmt.Errorf("%w: grid %q needs the rows, the piece to move and optionally the win length and popout",
+			ErrInvalidNotation, grid)
+	}
+
+	// Read the rows into cells, top row first
+	var cells [][]Piece
+	for _, rank := range strings.Split(fields[0], "/") {
+		var row []Piece
+		for i := 0; i < len(rank); i++ {
+			switch c := rank[i]; {
+			case c == '+':
+				row = append(row, PlayerIcon)
+			case c == '*':
+				row = append(row, CpuIcon)
+			case c >= '1' && c <= '9':
+				end := i + 1
+				for end < len(rank) && rank[end] >= '0' && rank[end] <= '9' {
+					end++
+				}
+				empty, _ := strconv.Atoi(rank[i:end])
+				for ; empty > 0 && len(row) <= MaxCols; empty-- {
+					row = append(row, Empty)
+				}
+				i = end - 1
+			default:
+				return C4Board{}, fmt.Errorf("%w: %q in grid row %q", ErrInvalidNotation, c, rank)
+			}
+		}
+		if len(cells) > 0 && len(row) != len(cells[0]) {
+			return C4Board{}, fmt.Errorf("%w: grid rows %q have different lengths", ErrInvalidNotation, fields[0])
+		}
+		cells = append(cells, row)
+	}
+
+	var toMove Piece
+	switch fields[1] {
+	case PlayerIcon.String():
+		toMove = PlayerIcon
+	case CpuIcon.String():
+		toMove = CpuIcon
+	default:
+		return C4Board{}, fmt.Errorf("%w: %q is not a piece to move", ErrInvalidNotation, fields[1])
+	}
+
+	winLength := uint64(WinLength)
+	if len(fields) > 2 {
+		var err error
+		if winLength, err = strconv.ParseUint(fields[2], 10, 8); err != nil {
+			return C4Board{}, fmt.Errorf("%w: %q is not a win length", ErrInvalidNotation, fields[2])
+		}
+	}
+	if len(fields) > 3 && fields[3] != "popout" {
+		return C4Board{}, fmt.Errorf("%w: %q is not a variant", ErrInvalidNotation, fields[3])
+	}
+
+	return boardFromCells(cells, toMove, uint(winLength), len(fields) > 3)
+}
+
+// boardFromCells sets up a board with the pieces in cells, the top row first, and toMove to move.
+// Returns ErrInvalidSize for a board that is too small or too large and ErrInvalidPosition
+// if the position can't come up in a game.
+func boardFromCells(cells [][]Piece, toMove Piece, winLength uint, popOut bool) (C4Board, error) {
+	var cols, rows uint
+	if len(cells) > 0 {
+		cols, rows = uint(len(cells[0])), uint(len(cells))
+	}
+	newBoard := NewConnectNBoard
+	if popOut {
+		newBoard = NewPopOutBoard
+	}
+	board, err := newBoard(cols, rows, winLength)
+	if err != nil {
+		return C4Board{}, err
+	}
+
+	for col := uint(0); col < cols; col++ {
+		for row := uint(0); row < rows; row++ {
+			piece := cells[rows-1-row][col]
+			if piece == Empty {
+				continue
+			}
+			if board.colCount[col] != row {
+				return C4Board{}, fmt.Errorf("%w: floating piece in column %d", ErrInvalidPosition, col+1)
+			}
+			board.position[col][row] = piece
+			board.hash ^= zobristPieces[col][row][piece]
+			board.colCount[col]++
+		}
+	}
+	board.next = toMove
+	board.turn = Player{Piece: toMove.opposite()}
+	if board.popOut {
+		board.history = &positionRecord{key: board.repetitionKey()}
+	}
+
+	if err := board.checkPossible(); err != nil {
+		return C4Board{}, err
+	}
+	return board, nil
+}
+
+// checkPossible returns ErrInvalidPosition if the pieces on the board can't come up in a game
+// with the piece to move it has.
+// Without PopOut pieces are never taken off the board, so the players have played the same number
+// of pieces give or take the first move, the player who has played more isn't to move,
+// and the game stops at the first winning line so both players can't have one.
+func (board C4Board) checkPossible() error {
+	if board.popOut {
+		return nil
+	}
+
+	var count [3]int
+	for col := uint(0); col < board.numCols; col++ {
+		for row := uint(0); row < board.colCount[col]; row++ {
+			count[board.position[col][row]]++
+		}
+	}
+	toMove, other := board.nextPiece(), board.nextPiece().opposite()
+	if count[toMove] > count[other] || count[other] > count[toMove]+1 {
+		return fmt.Errorf("%w: %d %v and %d %v with %v to move",
+			ErrInvalidPosition, count[PlayerIcon], PlayerIcon, count[CpuIcon], CpuIcon, toMove)
+	}
+
+	if board.hasWinningLine(toMove) && board.hasWinningLine(other) {
+		return fmt.Errorf("%w: both players have a winning line", ErrInvalidPosition)
+	}
+	if board.hasWinningLine(toMove) {
+		return fmt.Errorf("%w: %v has a winning line but it is %v's move again", ErrInvalidPosition, toMove, toMove)
+	}
+	return nil
+}
+
+// hasWinningLine reports whether piece has a winning line anywhere on the board
+func (board C4Board) hasWinningLine(piece Piece) bool {
+	for col := 0; col < int(board.numCols); col++ {
+		for row := 0; row < int(board.colCount[col]); row++ {
+			if board.position[col][row] != piece {
+				continue
+			}
+			for _, dir := range lineDirections {
+				if _, ok := board.lineFrom(col, row, dir); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package connect4
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseMoves(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     []Move
+	}{
+		{"", []Move{}},
+		{"4453", []Move{3, 3, 4, 2}},
+		{"44p4", []Move{3, 3, PopMove(3)}},
+		{"4 10 p4", []Move{3, 9, PopMove(3)}},
+		{"4104p10", []Move{3, 9, 3, PopMove(9)}},
+		{"1,7, 2", []Move{0, 6, 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoves(tt.sequence)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMoves(%q) = %v, %v, want %v", tt.sequence, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"0", "408", "4x", "44p", "11 11", "100", "p"} {
+		if _, err := ParseMoves(bad); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("ParseMoves(%q) error = %v, want ErrInvalidNotation", bad, err)
+		}
+	}
+}
+
+func TestPlayMoves(t *testing.T) {
+	b, err := NewBoard().PlayMoves("4453")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "7/7/7/7/3*3/2*++2 + 4"; b.Grid() != want {
+		t.Errorf("Grid() = %q, want %q", b.Grid(), want)
+	}
+
+	if _, err := NewBoard().PlayMoves("1111111"); !errors.Is(err, ErrColumnFull) {
+		t.Errorf("PlayMoves into a full column: error = %v, want ErrColumnFull", err)
+	}
+	if _, err := NewBoard().PlayMoves("8"); !errors.Is(err, ErrColumnOutOfRange) {
+		t.Errorf("PlayMoves off the board: error = %v, want ErrColumnOutOfRange", err)
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	b, err := ParseGrid("7/7/7/7/3*3/3+3 +")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := NewBoard().PlayMoves("44")
+	if b.String() != want.String() || b.Hash() != want.Hash() || b.nextPiece() != PlayerIcon {
+		t.Errorf("ParseGrid gave\n%s want\n%s", b, want)
+	}
+
+	tests := []struct {
+		grid string
+		err  error
+	}{
+		{"7/7/7/7/7/7", ErrInvalidNotation},
+		{"7/7/7/7/7/6x +", ErrInvalidNotation},
+		{"7/7/7/7/7/6 +", ErrInvalidNotation},
+		{"7/7/7/7/7/7 x", ErrInvalidNotation},
+		{"7/7/7/7/7/7 + 4 popin", ErrInvalidNotation},
+		{"3/3/3/3 +", ErrInvalidSize},
+		{"7/7/7/7/7/7 + 8", ErrInvalidSize},
+		{"7/7/7/7/3+3/7 *", ErrInvalidPosition},        // floating piece
+		{"7/7/7/7/7/2++3 +", ErrInvalidPosition},       // + has played twice in a row
+		{"7/7/7/7/7/3+3 +", ErrInvalidPosition},        // + played last and moves again
+		{"7/+6/+6/+6/+**4/***4 *", ErrInvalidPosition}, // + won but the game went on
+	}
+	for _, tt := range tests {
+		if _, err := ParseGrid(tt.grid); !errors.Is(err, tt.err) {
+			t.Errorf("ParseGrid(%q) error = %v, want %v", tt.grid, err, tt.err)
+		}
+	}
+
+	// PopOut boards can have any number of pieces each
+	if _, err := ParseGrid("7/7/7/7/7/2++3 + 4 popout"); err != nil {
+		t.Errorf("ParseGrid on a PopOut board: %v", err)
+	}
+}
+
+func TestNotationRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(16))
+	newBoards := []func() (C4Board, error){
+		func() (C4Board, error) { return NewBoard(), nil },
+		func() (C4Board, error) { return NewConnectNBoard(MaxCols, MaxRows, 5) },
+		func() (C4Board, error) { return NewConnectNBoard(MinCols, MinRows, 3) },
+		func() (C4Board, error) { return NewPopOutBoard(NumCols, NumRows, WinLength) },
+	}
+
+	for _, newBoard := range newBoards {
+		for game := 0; game < 50; game++ {
+			start, err := newBoard()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := start
+			var moves []Move
+			for !b.IsGameOver() && len(moves) < 60 {
+				legal := b.LegalMoves()
+				move := legal[r.Intn(len(legal))]
+				b = b.MakeMove(Player{Piece: b.nextPiece()}, move)
+				moves = append(moves, move)
+
+				sequence := FormatMoves(moves)
+				parsed, err := ParseMoves(sequence)
+				if err != nil || !reflect.DeepEqual(parsed, moves) {
+					t.Fatalf("ParseMoves(%q) = %v, %v, want %v", sequence, parsed, err, moves)
+				}
+				replayed, err := start.PlayMoves(sequence)
+				if err != nil || replayed.Grid() != b.Grid() {
+					t.Fatalf("PlayMoves(%q) = %q, %v, want %q", sequence, replayed.Grid(), err, b.Grid())
+				}
+
+				grid := b.Grid()
+				loaded, err := ParseGrid(grid)
+				if err != nil {
+					t.Fatalf("ParseGrid(%q): %v", grid, err)
+				}
+				if loaded.Grid() != grid || loaded.String() != b.String() || loaded.Hash() != b.Hash() {
+					t.Fatalf("ParseGrid(%q) gave %q\n%s", grid, loaded.Grid(), loaded)
+				}
+				if loaded.IsGameOver() != b.IsGameOver() || !reflect.DeepEqual(loaded.LegalMoves(), b.LegalMoves()) {
+					t.Fatalf("ParseGrid(%q) plays differently from the board it came from", grid)
+				}
+			}
+		}
+	}
+}
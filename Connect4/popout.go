@@ -0,0 +1,111 @@
+package connect4
+
+// ------------------------------------------------------
+// PopOut variant
+// ------------------------------------------------------
+// In PopOut a player may, instead of dropping a piece, pop one of their own pieces out of the
+// bottom of a column and everything above it drops down a row. A full board isn't a draw as
+// long as the player to move has a piece to pop.
+// A pop can finish lines for both players at once, the player who popped wins.
+// Games can go round in circles, so a position that comes up a third time with the same
+// player to move is a draw.
+
+// How many times a position has to come up for a PopOut game to be drawn
+const popOutRepetitions = 3
+
+// NewPopOutBoard is NewConnectNBoard for the PopOut variant, returning the same errors
+func NewPopOutBoard(cols, rows, winLength uint) (C4Board, error) {
+	b, err := NewConnectNBoard(cols, rows, winLength)
+	if err != nil {
+		return C4Board{}, err
+	}
+	b.popOut = true
+	b.history = &positionRecord{key: b.repetitionKey()}
+	return b, nil
+}
+
+// IsPopOut reports whether the board is played with the PopOut rules
+func (board C4Board) IsPopOut() bool {
+	return board.popOut
+}
+
+// positionRecord is one position of a PopOut game, they are linked from the newest back to the start.
+// Boards made from the same game share the older records so making a move only adds one.
+type positionRecord struct {
+	key  uint64 // repetitionKey of the position
+	prev *positionRecord
+}
+
+// repetitionKey tells positions apart for repetitions, the same pieces with a different player to move don't repeat
+func (board C4Board) repetitionKey() uint64 {
+	return board.hash ^ zobristMover[board.next]
+}
+
+// repeated reports whether the position has come up often enough for the game to be drawn
+func (board C4Board) repeated() bool {
+	return board.repeats() >= popOutRepetitions
+}
+
+// repeats returns how many times the position has come up in the game, this time included
+func (board C4Board) repeats() int {
+	if board.history == nil {
+		return 0
+	}
+	key, count := board.history.key, 0
+	for record := board.history; record != nil; record = record.prev {
+		if record.key == key {
+			count++
+		}
+	}
+	return count
+}
+
+// canPop reports whether piece can be popped out of the bottom of column col
+func (board C4Board) canPop(piece Piece, col uint) bool {
+	return board.popOut && piece != Empty && board.colCount[col] > 0 && board.position[col][0] == piece
+}
+
+// popPiece takes the bottom piece out of column col and drops the rest of the column down a row.
+// Does not check that there is a piece to pop (assumes legal move).
+func (board C4Board) popPiece(col uint) C4Board {
+	b := board
+	count := b.colCount[col]
+
+	for row := uint(0); row < count; row++ {
+		b.hash ^= zobristPieces[col][row][b.position[col][row]]
+	}
+	copy(b.position[col][:count-1], b.position[col][1:count])
+	b.position[col][count-1] = Empty
+	b.colCount[col]--
+	for row := uint(0); row < b.colCount[col]; row++ {
+		b.hash ^= zobristPieces[col][row][b.position[col][row]]
+	}
+
+	return b
+}
+
+// cancelledLines is how much the finished lines of the player who didn't make the last move add
+// to Evaluate's score for player, when the player who did make it has finished lines too.
+// Only a pop can finish lines for both players and the player who popped wins, so those lines don't count.
+func (board C4Board) cancelledLines(player Piece, directions ...[]Segment) float32 {
+	mover := board.turn.Piece
+	moverLines := false
+	var score float32
+	for _, segments := range directions {
+		for _, segment := range segments {
+			if !segmentEquivalent(segment) {
+				continue
+			}
+			if segment[0] == mover {
+				moverLines = true
+			} else {
+				score += CalculateScore(segment, player)
+			}
+		}
+	}
+
+	if !moverLines {
+		return 0
+	}
+	return score
+}
@@ -0,0 +1,157 @@
+package connect4
+
+import (
+	"errors"
+	"testing"
+)
+
+// playPopOut plays moves on an empty standard PopOut board, alternating from PlayerIcon
+func playPopOut(t *testing.T, moves ...Move) C4Board {
+	t.Helper()
+	b, err := NewPopOutBoard(NumCols, NumRows, WinLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Player{Piece: PlayerIcon}
+	for _, move := range moves {
+		if b, err = b.TryMove(p, move); err != nil {
+			t.Fatalf("playing %v: %v\n%s", moves, err, b)
+		}
+		p = p.Opponent()
+	}
+	return b
+}
+
+func TestPopShiftsColumn(t *testing.T) {
+	b := playPopOut(t, 0, 0, 1, 2, PopMove(0))
+
+	// the same pieces dropped without a pop
+	want := NewBoard()
+	want = want.MakeMove(Player{Piece: CpuIcon}, 0)
+	want = want.MakeMove(Player{Piece: PlayerIcon}, 1)
+	want = want.MakeMove(Player{Piece: CpuIcon}, 2)
+
+	if b.String() != want.String() || b.Hash() != want.Hash() {
+		t.Errorf("after the pop\n%s want\n%s", b, want)
+	}
+}
+
+func TestPopLegalMoves(t *testing.T) {
+	b := playPopOut(t, 0, 1, 2)
+
+	// CpuIcon is to move and only has the bottom of column 1
+	var pops []Move
+	for _, move := range b.LegalMoves() {
+		if move.IsPop() {
+			pops = append(pops, move)
+		}
+	}
+	if len(pops) != 1 || pops[0] != PopMove(1) {
+		t.Errorf("pops = %v, want [p1]", pops)
+	}
+	if len(b.OrderedMoves()) != len(b.LegalMoves()) {
+		t.Errorf("OrderedMoves() = %v, LegalMoves() = %v", b.OrderedMoves(), b.LegalMoves())
+	}
+
+	cpu := Player{Piece: CpuIcon}
+	for _, col := range []uint{0, 3} {
+		if _, err := b.TryMove(cpu, PopMove(col)); !errors.Is(err, ErrCannotPop) {
+			t.Errorf("popping column %d: error = %v, want ErrCannotPop", col, err)
+		}
+	}
+	if _, err := NewBoard().MakeMove(cpu, 0).TryMove(cpu, PopMove(0)); !errors.Is(err, ErrCannotPop) {
+		t.Errorf("popping without PopOut: error = %v, want ErrCannotPop", err)
+	}
+}
+
+func TestPopWinsForBothPlayers(t *testing.T) {
+	// column 0 is + * + from the bottom, row 0 has * in columns 1-3 and row 1 has +,
+	// popping column 0 finishes both rows at once
+	b := playPopOut(t, 0, 0, 0, 1, 1, 2, 2, 3, 3, 6)
+	if b.IsGameOver() {
+		t.Fatalf("game over before the pop\n%s", b)
+	}
+
+	popped := b.MakeMove(Player{Piece: PlayerIcon}, PopMove(0))
+	result := popped.Result()
+	if result.Outcome != Win || result.Winner != PlayerIcon {
+		t.Errorf("Result() = %v, want a win for the player who popped\n%s", result, popped)
+	}
+	if score := popped.Evaluate(PlayerIcon); score <= 0 {
+		t.Errorf("Evaluate(PlayerIcon) = %v, want the popper ahead", score)
+	}
+
+	for _, e := range []Engine{GreedyEngine{}, NewMiniMaxEngine(2), NewMCTSEngine(2000, 0, 1)} {
+		if move := e.BestMove(b, Player{Piece: PlayerIcon}); move != PopMove(0) {
+			t.Errorf("%s played %v, want the winning pop p0", e.Name(), move)
+		}
+	}
+}
+
+func TestPopWinsForOpponent(t *testing.T) {
+	// popping column 0 drops a * next to the three in row 0
+	b := playPopOut(t, 0, 0, 6, 1, 6, 2, 5, 3)
+	popped := b.MakeMove(Player{Piece: PlayerIcon}, PopMove(0))
+
+	if result := popped.Result(); result.Outcome != Win || result.Winner != CpuIcon {
+		t.Errorf("Result() = %v, want a win for *\n%s", result, popped)
+	}
+}
+
+func TestPopOutRepetitionDraw(t *testing.T) {
+	// dropping and popping the same two pieces goes round in circles,
+	// the position after the first drop comes up for the third time on move 9
+	var moves []Move
+	for len(moves) < 9 {
+		moves = append(moves, []Move{0, 1, PopMove(0), PopMove(1)}...)
+	}
+	moves = moves[:9]
+
+	if b := playPopOut(t, moves[:8]...); b.IsDraw() {
+		t.Errorf("drawn after 8 moves\n%s", b)
+	}
+	b := playPopOut(t, moves...)
+	if !b.IsDraw() {
+		t.Errorf("not drawn after the third repetition\n%s", b)
+	}
+	if result := b.Result(); result.Outcome != Draw {
+		t.Errorf("Result() = %v, want a draw", result)
+	}
+	if _, err := b.TryMove(Player{Piece: CpuIcon}, 3); !errors.Is(err, ErrGameOver) {
+		t.Errorf("moving after the draw: error = %v, want ErrGameOver", err)
+	}
+}
+
+func TestPopOutFullBoardGoesOn(t *testing.T) {
+	// fill the board column by column in pairs so nobody gets four in a row
+	var moves []Move
+	for _, pair := range [][2]Move{{0, 1}, {0, 1}, {0, 1}, {1, 0}, {1, 0}, {1, 0},
+		{2, 3}, {2, 3}, {2, 3}, {3, 2}, {3, 2}, {3, 2},
+		{4, 5}, {4, 5}, {4, 5}, {5, 4}, {5, 4}, {5, 4}} {
+		moves = append(moves, pair[0], pair[1])
+	}
+	moves = append(moves, 6, 6, 6, 6, 6, 6)
+	b := playPopOut(t, moves...)
+	if b.emptyCells() != 0 || b.IsWin() {
+		t.Fatalf("want a full board with no winner\n%s", b)
+	}
+
+	if b.IsDraw() {
+		t.Errorf("full PopOut board with pops left is a draw\n%s", b)
+	}
+	for _, move := range b.LegalMoves() {
+		if !move.IsPop() {
+			t.Errorf("LegalMoves() has drop %v on a full board", move)
+		}
+	}
+}
+
+func TestPopOutUnsupported(t *testing.T) {
+	b := playPopOut(t, 3)
+	if _, err := ToBitBoard(b); !errors.Is(err, ErrUnsupportedVariant) {
+		t.Errorf("ToBitBoard error = %v, want ErrUnsupportedVariant", err)
+	}
+	if _, err := Solve(b, Player{Piece: CpuIcon}); !errors.Is(err, ErrUnsupportedVariant) {
+		t.Errorf("Solve error = %v, want ErrUnsupportedVariant", err)
+	}
+}
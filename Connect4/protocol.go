@@ -0,0 +1,346 @@
+package connect4
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------
+// Engine protocol - driving the engine from other programs
+// ------------------------------------------------------
+// ServeProtocol speaks a line-based protocol modelled on the UCI protocol of chess engines, so GUIs,
+// scripts and other engines can use the search. Commands come in one per line and replies go out
+// one per line, moves are written in the move sequence notation ("4" or "p4", columns counted from 1).
+//
+//	uci                               replies with id lines and uciok
+//	isready                           replies readyok, also while searching
+//	ucinewgame                        a new game on the standard board, forgetting what was searched
+//	position startpos [moves 4 4 3]   the standard board with the moves played on it
+//	position grid <grid> [moves ...]  a board written as a grid, see ParseGrid, with the moves played on it
+//	go [depth n] [movetime ms]        searches the position, see below
+//	stop                              ends the search, which replies with its bestmove
+//	                                  (any other command waits for a search with a depth or time limit,
+//	                                  the commands sent after it are read while it waits and stop still ends it)
+//	quit                              ends the search and the protocol
+//
+// go searches with iterative deepening until it reaches depth (in plies past the move, so depth 0
+// only looks at the moves themselves),
+// runs out of movetime or the time given by wtime, btime, winc and binc for the first (w) and the
+// second (b) player, or is stopped. Without any of them, or with infinite, it searches until stop.
+// After each depth it finishes it sends
+//
+//	info depth 6 score cp 42 nodes 10234 time 15 nps 682266 pv 4 4 3 5 4 3
+//
+// with the score from the side to move's point of view, and when it is done
+//
+//	bestmove 4
+//
+// or "bestmove (none)" if the game is over. Anything that can't be done is answered with an
+// "info string" line saying why.
+
+// ProtocolID is what the engine says it is in reply to uci
+type ProtocolID struct {
+	Name   string
+	Author string
+}
+
+// DefaultProtocolID is what the engine says it is unless it is told otherwise
+var DefaultProtocolID = ProtocolID{Name: "Connect4 (GoLangProjects)", Author: "accal"}
+
+// How much of the time left on a player's clock a move is given, 1/clockMoves of it
+const clockMoves = 20
+
+// protocolServer holds the state of a ServeProtocol session
+type protocolServer struct {
+	out   io.Writer
+	mu    sync.Mutex // held while writing a line, the search writes from its own goroutine
+	board C4Board
+	table *TranspositionTable
+
+	lines   <-chan string // the commands as they are read, closed at the end of in
+	pending []string      // commands read while waiting for a search, to be carried out next
+	ended   bool          // lines was closed while waiting for a search
+
+	stop     func()        // ends the running search, nil when there isn't one
+	done     chan struct{} // closed once the running search has sent its bestmove
+	infinite bool          // the running search goes on until it is stopped
+}
+
+// ServeProtocol reads commands from in and writes replies to out until quit or the end of in,
+// see the protocol above, giving id as its name and author. It returns an error only if in can't be read.
+func ServeProtocol(in io.Reader, out io.Writer, id ProtocolID) error {
+	// the commands are read on their own goroutine so a stop can end a search another command waits for
+	done := make(chan struct{})
+	defer close(done)
+	lines := make(chan string)
+	var readErr error
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+		readErr = scanner.Err()
+	}()
+
+	srv := &protocolServer{out: out, board: NewBoard(), table: NewTranspositionTable(DefaultTableSize), lines: lines}
+	defer srv.finishSearch()
+
+	for {
+		line, ok := srv.nextCommand()
+		if !ok {
+			return readErr
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch command, args := fields[0], fields[1:]; command {
+		case "uci":
+			srv.send("id name " + id.Name)
+			srv.send("id author " + id.Author)
+			srv.send("uciok")
+		case "isready":
+			srv.send("readyok")
+		case "ucinewgame":
+			srv.finishSearch()
+			srv.board, srv.table = NewBoard(), NewTranspositionTable(DefaultTableSize)
+		case "position":
+			srv.finishSearch()
+			if err := srv.position(args); err != nil {
+				srv.send("info string " + err.Error())
+			}
+		case "go":
+			srv.finishSearch()
+			if err := srv.goSearch(args); err != nil {
+				srv.send("info string " + err.Error())
+			}
+		case "stop":
+			srv.stopSearch()
+		case "quit":
+			srv.stopSearch()
+			return nil
+		default:
+			srv.send("info string unknown command " + command)
+		}
+	}
+}
+
+// nextCommand returns the next command to carry out, returning false at the end of the input
+func (srv *protocolServer) nextCommand() (string, bool) {
+	if len(srv.pending) > 0 {
+		line := srv.pending[0]
+		srv.pending = srv.pending[1:]
+		return line, true
+	}
+	if srv.ended {
+		return "", false
+	}
+	line, ok := <-srv.lines
+	return line, ok
+}
+
+// send writes line to out
+func (srv *protocolServer) send(line string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	fmt.Fprintln(srv.out, line)
+}
+
+// position sets the board from the arguments of a position command, leaving it alone if they can't be played
+func (srv *protocolServer) position(args []string) error {
+	var moves []string
+	for i, arg := range args {
+		if arg == "moves" {
+			args, moves = args[:i], args[i+1:]
+			break
+		}
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: position needs startpos or a grid", ErrInvalidNotation)
+	}
+
+	var board C4Board
+	var err error
+	switch args[0] {
+	case "startpos":
+		if len(args) > 1 {
+			return fmt.Errorf("%w: startpos takes no arguments, got %q", ErrInvalidNotation, strings.Join(args[1:], " "))
+		}
+		board = NewBoard()
+	case "grid":
+		board, err = ParseGrid(strings.Join(args[1:], " "))
+	default:
+		return fmt.Errorf("%w: position %q is neither startpos nor a grid", ErrInvalidNotation, args[0])
+	}
+	if err == nil && len(moves) > 0 {
+		board, err = board.PlayMoves(strings.Join(moves, " "))
+	}
+	if err != nil {
+		return err
+	}
+	srv.board = board
+	return nil
+}
+
+// searchLimits is what a go command asks of the search
+type searchLimits struct {
+	depth    uint          // plies past the move, 0 for no limit
+	moveTime time.Duration // 0 for no limit
+	depthSet bool          // depth was given, 0 only searches the moves themselves
+	infinite bool          // wait for stop even once the search is over
+}
+
+// parseGo reads the arguments of a go command for a search with piece to move
+func parseGo(args []string, piece Piece) (searchLimits, error) {
+	var limits searchLimits
+	var clock, increment time.Duration
+	for i := 0; i < len(args); i++ {
+		name := args[i]
+		if name == "infinite" {
+			limits.infinite = true
+			continue
+		}
+		if i+1 == len(args) {
+			return limits, fmt.Errorf("%w: go %s needs a value", ErrInvalidNotation, name)
+		}
+		i++
+		value, err := strconv.ParseUint(args[i], 10, 32)
+		if err != nil {
+			return limits, fmt.Errorf("%w: go %s %q is not a number", ErrInvalidNotation, name, args[i])
+		}
+		ms := time.Duration(value) * time.Millisecond
+
+		ownClock := (name[0] == 'w') == (piece == PlayerIcon)
+		switch name {
+		case "depth":
+			limits.depth, limits.depthSet = uint(value), true
+		case "movetime":
+			limits.moveTime = ms
+		case "wtime", "btime":
+			if ownClock {
+				clock = ms
+			}
+		case "winc", "binc":
+			if ownClock {
+				increment = ms
+			}
+		default:
+			return limits, fmt.Errorf("%w: go %s is not a search limit", ErrInvalidNotation, name)
+		}
+	}
+
+	if clock > 0 && limits.moveTime == 0 {
+		limits.moveTime = clock/clockMoves + increment/2
+	}
+	if !limits.depthSet && limits.moveTime == 0 {
+		limits.infinite = true
+	}
+	return limits, nil
+}
+
+// goSearch starts searching the board with the arguments of a go command,
+// the search sends its info lines and bestmove from its own goroutine
+func (srv *protocolServer) goSearch(args []string) error {
+	board := srv.board
+	p := Player{Piece: board.nextPiece()}
+	limits, err := parseGo(args, p.Piece)
+	if err != nil {
+		return err
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if limits.moveTime > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), limits.moveTime)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	srv.stop, srv.done, srv.infinite = cancel, make(chan struct{}), limits.infinite
+	go func(done chan struct{}) {
+		defer close(done)
+		if board.IsGameOver() {
+			srv.send("bestmove (none)")
+			return
+		}
+
+		// a maxDepth of 0 doesn't limit the search, depth 0 is stopped once it is done instead
+		result := ConcurrentSearchReporting(ctx, board, p, limits.depth, srv.table, func(r SearchResult) {
+			srv.send(infoLine(r))
+			if limits.depthSet && r.Depth >= limits.depth {
+				cancel()
+			}
+		})
+		if limits.infinite {
+			<-ctx.Done() // an infinite search only answers when it is stopped
+		}
+		srv.send("bestmove " + FormatMoves([]Move{result.Move}))
+	}(srv.done)
+	return nil
+}
+
+// finishSearch waits for the running search to reach its depth or run out of time,
+// stopping it if it would go on until it was stopped. Commands that change what is
+// searched wait for it, so a script can send go and position one after the other.
+// The commands read while it waits are kept for after it, a stop or quit among them ends the search.
+func (srv *protocolServer) finishSearch() {
+	if srv.infinite {
+		srv.stopSearch()
+	}
+	for srv.done != nil {
+		select {
+		case <-srv.done:
+			srv.stopSearch()
+		case line, ok := <-srv.lines:
+			if !ok {
+				srv.lines, srv.ended = nil, true
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "stop":
+				srv.stopSearch()
+				continue
+			case "quit":
+				srv.stopSearch()
+			}
+			srv.pending = append(srv.pending, line)
+		}
+	}
+}
+
+// stopSearch ends the running search and waits for its bestmove
+func (srv *protocolServer) stopSearch() {
+	if srv.stop == nil {
+		return
+	}
+	srv.stop()
+	<-srv.done
+	srv.stop, srv.done = nil, nil
+}
+
+// infoLine writes a finished iteration of the search as an info line
+func infoLine(r SearchResult) string {
+	pv := make([]string, len(r.PV))
+	for i, move := range r.PV {
+		pv[i] = FormatMoves([]Move{move})
+	}
+	return fmt.Sprintf("info depth %d score cp %d nodes %d time %d nps %.0f pv %s",
+		r.Depth, int(math.Round(float64(r.Score))), r.Nodes, r.Elapsed.Milliseconds(), r.NodesPerSecond(), strings.Join(pv, " "))
+}
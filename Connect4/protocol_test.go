@@ -0,0 +1,133 @@
+package connect4
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProtocolScript(t *testing.T) {
+	script := strings.Join([]string{
+		"uci",
+		"isready",
+		"position startpos moves 1 2 1 2 1 2",
+		"go depth 2",
+		"position startpos moves 4 44",
+		"go depth 1 wibble",
+		"fly",
+		"go depth 0",
+		"position startpos moves 1 2 1 2 1 2 1",
+		"go depth 3",
+		"position grid 7/7/7/7/3*3/3+3 + 4 moves 3",
+		"go depth 0",
+	}, "\n")
+	var out strings.Builder
+	if err := ServeProtocol(strings.NewReader(script), &out, DefaultProtocolID); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"id name " + DefaultProtocolID.Name,
+		"id author " + DefaultProtocolID.Author,
+		"uciok",
+		"readyok",
+		"info depth 0 ",
+		"info depth 1 ",
+		"info depth 2 ",
+		"bestmove 1", // the win in the first column
+		"info string ",
+		"info string ",
+		"info string unknown command fly",
+		"info depth 0 ", // the last good position is still searched
+		"bestmove 1",
+		"bestmove (none)",                 // the game is over
+		"info depth 0 score cp 6 nodes 7", // 4 4 3 written as a grid
+		"bestmove 3",
+	}
+	for i, prefix := range want {
+		if i >= len(lines) || !strings.HasPrefix(lines[i], prefix) {
+			t.Fatalf("reply %d: want %q, got\n%s", i, prefix, strings.Join(lines, "\n"))
+		}
+	}
+	if len(lines) != len(want) {
+		t.Errorf("%d replies, want %d:\n%s", len(lines), len(want), strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[6], " pv 1") {
+		t.Errorf("info line without the winning move in its pv: %q", lines[6])
+	}
+}
+
+func TestProtocolStop(t *testing.T) {
+	in, commands := io.Pipe()
+	replies, out := io.Pipe()
+	served := make(chan error)
+	go func() { served <- ServeProtocol(in, out, DefaultProtocolID) }()
+	read := bufio.NewScanner(replies)
+
+	io.WriteString(commands, "go infinite\n")
+	if !read.Scan() || !strings.HasPrefix(read.Text(), "info depth 0 ") {
+		t.Fatalf("first reply to go infinite: %q", read.Text())
+	}
+	io.WriteString(commands, "isready\n")
+	for read.Scan() && strings.HasPrefix(read.Text(), "info depth") {
+	}
+	if read.Text() != "readyok" {
+		t.Fatalf("reply to isready while searching: %q", read.Text())
+	}
+
+	io.WriteString(commands, "stop\n")
+	for read.Scan() && strings.HasPrefix(read.Text(), "info depth") {
+	}
+	if !strings.HasPrefix(read.Text(), "bestmove ") {
+		t.Fatalf("reply to stop: %q", read.Text())
+	}
+
+	io.WriteString(commands, "quit\n")
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProtocolQuitWhileWaiting(t *testing.T) {
+	// position waits for the search to reach depth 40, which it won't any time soon, the quit read meanwhile ends it
+	script := "uci\ngo depth 40\nposition startpos\nquit\n"
+	var out strings.Builder
+	if err := ServeProtocol(strings.NewReader(script), &out, ProtocolID{Name: "Mine", Author: "Me"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 4 || lines[0] != "id name Mine" || lines[1] != "id author Me" || !strings.HasPrefix(lines[len(lines)-1], "bestmove ") {
+		t.Errorf("replies:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestProtocolStopWhileWaiting(t *testing.T) {
+	in, commands := io.Pipe()
+	replies, out := io.Pipe()
+	served := make(chan error)
+	go func() { served <- ServeProtocol(in, out, DefaultProtocolID) }()
+	read := bufio.NewScanner(replies)
+
+	io.WriteString(commands, "go depth 40\n")
+	if !read.Scan() || !strings.HasPrefix(read.Text(), "info depth 0 ") {
+		t.Fatalf("first reply to go depth 40: %q", read.Text())
+	}
+	// position waits for the search, the stop sent after it is read anyway
+	go io.WriteString(commands, "position startpos moves 4\nstop\nisready\n")
+	for read.Scan() && strings.HasPrefix(read.Text(), "info depth") {
+	}
+	if !strings.HasPrefix(read.Text(), "bestmove ") {
+		t.Fatalf("reply to stop: %q", read.Text())
+	}
+	if !read.Scan() || read.Text() != "readyok" {
+		t.Fatalf("reply to isready after the stop: %q", read.Text())
+	}
+
+	io.WriteString(commands, "quit\n")
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
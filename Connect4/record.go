@@ -0,0 +1,497 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ------------------------------------------------------
+// Game records
+// ------------------------------------------------------
+// Finished games are archived as text in the style of chess's PGN: a block of tags followed by
+// the moves, with the result at the end. Any number of games can follow each other in a file.
+//
+//	[Event "Connect 4"]
+//	[Date "2026.10.18"]
+//	[First "Player"]
+//	[Second "Computer"]
+//	[SecondEngine "Minimax (depth 4)"]
+//	[SecondDepth "4"]
+//	[Board "7x6"]
+//	[WinLength "4"]
+//	[Result "1-0"]
+//
+//	1. 4 4 {[%eval -12] [%depth 4]} 2. 5 {threatens both sides} 3 ... 1-0
+//
+// Moves are in the move sequence notation (columns counted from 1, p4 pops column 4) and each
+// pair of moves is numbered. A comment in braces belongs to the move before it, [%eval score]
+// and [%depth plies] in it are the score the mover's engine gave the move and how deep it looked.
+// Games on a PopOut board have a [Variant "PopOut"] tag and games that didn't start on an empty
+// board a [Position] tag with the starting grid.
+
+// The results a game record can end with
+const (
+	ResultFirstWins  = "1-0"
+	ResultSecondWins = "0-1"
+	ResultDraw       = "1/2-1/2"
+	ResultUnfinished = "*"
+)
+
+// Tag is one header line of a game record
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// RecordedMove is a move of a game record
+type RecordedMove struct {
+	Move    Move
+	Comment string  // free text, without the eval and depth
+	Eval    float32 // the score the mover's engine gave the move, if HasEval
+	Depth   uint    // how many plies deep the engine looked for Eval, 0 if it didn't say
+	HasEval bool
+}
+
+// GameRecord is a game written down for the archive
+type GameRecord struct {
+	Tags  []Tag // in the order they are written
+	Moves []RecordedMove
+}
+
+// NewGameRecord returns a record of the game in s so far, without any evals
+func NewGameRecord(s *Session) GameRecord {
+	var r GameRecord
+	date := time.Now()
+	if len(s.history) > 0 {
+		date = s.history[0].Time
+	}
+	r.SetTag("Event", "Connect 4")
+	r.SetTag("Date", date.Format("2006.01.02"))
+
+	for i, p := range s.players {
+		seat := [2]string{"First", "Second"}[i]
+		r.SetTag(seat, p.Name)
+		if p.Engine == nil {
+			continue
+		}
+		r.SetTag(seat+"Engine", p.Engine.Name())
+		if depth, ok := engineDepth(p.Engine); ok {
+			r.SetTag(seat+"Depth", strconv.FormatUint(uint64(depth), 10))
+		}
+	}
+
+	r.SetTag("Board", fmt.Sprintf("%dx%d", s.start.Cols(), s.start.Rows()))
+	r.SetTag("WinLength", strconv.FormatUint(uint64(s.start.WinLength()), 10))
+	if s.start.IsPopOut() {
+		r.SetTag("Variant", "PopOut")
+	}
+	if s.start.emptyCells() != s.start.Cols()*s.start.Rows() {
+		r.SetTag("Position", s.start.Grid())
+	}
+	r.SetTag("Result", s.resultTag())
+
+	for _, record := range s.history {
+		r.Moves = append(r.Moves, RecordedMove{Move: record.Col})
+	}
+	return r
+}
+
+// engineDepth returns how many plies deep e searches, if it searches to a set depth
+func engineDepth(e Engine) (uint, bool) {
+	switch e := e.(type) {
+	case *MiniMaxEngine:
+		return e.Depth, true
+	case *TimedEngine:
+		return e.MaxDepth, e.MaxDepth > 0
+	case *SolverEngine:
+		return engineDepth(e.Fallback)
+	}
+	return 0, false
+}
+
+// resultTag is the game's result as it is written in a record
+func (s *Session) resultTag() string {
+	result := s.Result()
+	switch {
+	case result.Outcome == Draw:
+		return ResultDraw
+	case result.Outcome != Win:
+		return ResultUnfinished
+	case s.players[0].Piece == result.Winner:
+		return ResultFirstWins
+	default:
+		return ResultSecondWins
+	}
+}
+
+// Tag returns the value of the tag called name, "" if there isn't one
+func (r *GameRecord) Tag(name string) string {
+	for _, tag := range r.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// SetTag sets the tag called name to value, adding it at the end if there isn't one yet
+func (r *GameRecord) SetTag(name, value string) {
+	for i := range r.Tags {
+		if r.Tags[i].Name == name {
+			r.Tags[i].Value = value
+			return
+		}
+	}
+	r.Tags = append(r.Tags, Tag{Name: name, Value: value})
+}
+
+// Result is the Result tag, ResultUnfinished if it is missing
+func (r *GameRecord) Result() string {
+	if result := r.Tag("Result"); result != "" {
+		return result
+	}
+	return ResultUnfinished
+}
+
+// Start returns the board the game started on, as the Board, WinLength, Variant and Position tags describe it.
+// Without them it is the standard board.
+func (r *GameRecord) Start() (C4Board, error) {
+	if grid := r.Tag("Position"); grid != "" {
+		return ParseGrid(grid)
+	}
+
+	cols, rows, winLength := uint(NumCols), uint(NumRows), uint(WinLength)
+	if size := r.Tag("Board"); size != "" {
+		if _, err := fmt.Sscanf(size, "%dx%d", &cols, &rows); err != nil {
+			return C4Board{}, fmt.Errorf("%w: board size %q", ErrInvalidRecord, size)
+		}
+	}
+	if length := r.Tag("WinLength"); length != "" {
+		n, err := strconv.ParseUint(length, 10, 8)
+		if err != nil {
+			return C4Board{}, fmt.Errorf("%w: win length %q", ErrInvalidRecord, length)
+		}
+		winLength = uint(n)
+	}
+	switch variant := r.Tag("Variant"); variant {
+	case "", "Standard":
+		return NewConnectNBoard(cols, rows, winLength)
+	case "PopOut":
+		return NewPopOutBoard(cols, rows, winLength)
+	default:
+		return C4Board{}, fmt.Errorf("%w: variant %q", ErrInvalidRecord, variant)
+	}
+}
+
+// Final returns the board at the end of the game, returning the error of the first move that isn't legal
+func (r *GameRecord) Final() (C4Board, error) {
+	board, err := r.Start()
+	if err != nil {
+		return C4Board{}, err
+	}
+	for i, move := range r.Moves {
+		if board, err = board.TryMove(Player{Piece: board.nextPiece()}, move.Move); err != nil {
+			return C4Board{}, fmt.Errorf("move %d of the record: %w", i+1, err)
+		}
+	}
+	return board, nil
+}
+
+// String writes the record out in the game record format
+func (r GameRecord) String() string {
+	var sb strings.Builder
+	for _, tag := range r.Tags {
+		fmt.Fprintf(&sb, "[%s %s]\n", tag.Name, strconv.Quote(tag.Value))
+	}
+	sb.WriteString("\n")
+
+	// the moves are wrapped at 80 columns like PGN
+	line := 0
+	write := func(token string) {
+		if line > 0 && line+1+len(token) > 80 {
+			sb.WriteString("\n")
+			line = 0
+		} else if line > 0 {
+			sb.WriteString(" ")
+			line++
+		}
+		sb.WriteString(token)
+		line += len(token)
+	}
+	for i, move := range r.Moves {
+		if i%2 == 0 {
+			write(strconv.Itoa(i/2+1) + ".")
+		}
+		write(FormatMoves([]Move{move.Move}))
+		if comment := move.comment(); comment != "" {
+			write(comment)
+		}
+	}
+	write(r.Result())
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// comment is the move's comment in braces with the eval and depth in front, "" if it has none of them
+func (m RecordedMove) comment() string {
+	var parts []string
+	if m.HasEval {
+		parts = append(parts, "[%eval "+strconv.FormatFloat(float64(m.Eval), 'f', -1, 32)+"]")
+		if m.Depth > 0 {
+			parts = append(parts, fmt.Sprintf("[%%depth %d]", m.Depth))
+		}
+	}
+	if text := strings.TrimSpace(commentBraces.Replace(m.Comment)); text != "" {
+		parts = append(parts, text)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// commentBraces turns braces in comments into parentheses, a comment can't hold the brace that would end it
+var commentBraces = strings.NewReplacer("{", "(", "}", ")")
+
+// WriteRecord appends the record to w with a blank line after it, so records can follow each other in a file
+func WriteRecord(w io.Writer, r GameRecord) error {
+	_, err := io.WriteString(w, r.String()+"\n")
+	return err
+}
+
+// AppendRecord adds the record to the end of the archive file at path, creating it if it doesn't exist
+func AppendRecord(path string, r GameRecord) (err error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return WriteRecord(f, r)
+}
+
+// ReadRecords reads every game record in rd. Each game's moves are checked to be legal.
+// Returns ErrInvalidRecord if the text isn't in the game record format,
+// or the error of the first move of a game that isn't legal.
+func ReadRecords(rd io.Reader) ([]GameRecord, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	p := recordParser{text: string(data)}
+	var records []GameRecord
+	for {
+		r, err := p.next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err == nil {
+			_, err = r.Final()
+		}
+		if err != nil {
+			return records, fmt.Errorf("game %d: %w", len(records)+1, err)
+		}
+		records = append(records, r)
+	}
+}
+
+// ParseRecord reads a single game record, see ReadRecords
+func ParseRecord(text string) (GameRecord, error) {
+	records, err := ReadRecords(strings.NewReader(text))
+	if err != nil {
+		return GameRecord{}, err
+	}
+	if len(records) != 1 {
+		return GameRecord{}, fmt.Errorf("%w: %d games where one was expected", ErrInvalidRecord, len(records))
+	}
+	return records[0], nil
+}
+
+// recordParser reads game records from text one game at a time
+type recordParser struct {
+	text string
+	pos  int
+}
+
+// next reads the next game, returning io.EOF if there are none left
+func (p *recordParser) next() (GameRecord, error) {
+	var r GameRecord
+	p.skipSpace()
+	if p.pos == len(p.text) {
+		return r, io.EOF
+	}
+
+	for p.pos < len(p.text) && p.text[p.pos] == '[' {
+		tag, err := p.tag()
+		if err != nil {
+			return r, err
+		}
+		r.Tags = append(r.Tags, tag)
+		p.skipSpace()
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos == len(p.text) {
+			return r, fmt.Errorf("%w: the moves have no result at the end", ErrInvalidRecord)
+		}
+
+		if p.text[p.pos] == '{' {
+			end := strings.IndexByte(p.text[p.pos:], '}')
+			if end < 0 {
+				return r, fmt.Errorf("%w: comment without a closing brace", ErrInvalidRecord)
+			}
+			if len(r.Moves) == 0 {
+				return r, fmt.Errorf("%w: comment before the first move", ErrInvalidRecord)
+			}
+			parseComment(&r.Moves[len(r.Moves)-1], p.text[p.pos+1:p.pos+end])
+			p.pos += end + 1
+			continue
+		}
+
+		token := p.token()
+		switch {
+		case token == ResultFirstWins || token == ResultSecondWins || token == ResultDraw || token == ResultUnfinished:
+			if token != r.Result() {
+				return r, fmt.Errorf("%w: the moves end in %s but the Result tag is %s", ErrInvalidRecord, token, r.Result())
+			}
+			return r, nil
+		case strings.HasSuffix(token, "."):
+			// move number
+		default:
+			moves, err := ParseMoves(token)
+			if err != nil || len(moves) != 1 {
+				return r, fmt.Errorf("%w: %q is not a move", ErrInvalidRecord, token)
+			}
+			r.Moves = append(r.Moves, RecordedMove{Move: moves[0]})
+		}
+	}
+}
+
+// tag reads a [Name "Value"] tag
+func (p *recordParser) tag() (Tag, error) {
+	start := p.pos
+	p.pos++ // [
+	name := p.token()
+	p.skipSpace()
+	quoted, err := strconv.QuotedPrefix(p.text[p.pos:])
+	if err == nil {
+		p.pos += len(quoted)
+		p.skipSpace()
+	}
+	if name == "" || err != nil || p.pos == len(p.text) || p.text[p.pos] != ']' {
+		line, _, _ := strings.Cut(p.text[start:], "\n")
+		return Tag{}, fmt.Errorf("%w: tag %q", ErrInvalidRecord, line)
+	}
+	p.pos++ // ]
+
+	value, _ := strconv.Unquote(quoted)
+	return Tag{Name: name, Value: value}, nil
+}
+
+// token reads everything up to the next space or comment
+func (p *recordParser) token() string {
+	start := p.pos
+	for p.pos < len(p.text) && p.text[p.pos] != '{' && !unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+func (p *recordParser) skipSpace() {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// parseComment takes the eval and depth out of comment onto move, the rest is the move's Comment
+func parseComment(move *RecordedMove, comment string) {
+	comment = strings.Join(strings.Fields(comment), " ")
+
+	for _, command := range []string{"eval", "depth"} {
+		start := strings.Index(comment, "[%"+command+" ")
+		if start < 0 {
+			continue
+		}
+		end := strings.IndexByte(comment[start:], ']')
+		if end < 0 {
+			continue
+		}
+		value := comment[start+len(command)+3 : start+end]
+		switch command {
+		case "eval":
+			if eval, err := strconv.ParseFloat(value, 32); err == nil {
+				move.Eval, move.HasEval = float32(eval), true
+			}
+		case "depth":
+			if depth, err := strconv.ParseUint(value, 10, 32); err == nil {
+				move.Depth = uint(depth)
+			}
+		}
+		comment = comment[:start] + comment[start+end+1:]
+	}
+	move.Comment = strings.Join(strings.Fields(comment), " ")
+}
+
+// GameRecorder is a Listener that keeps a record of a session as it is played,
+// taking back moves that are undone. Evals are added to moves with Annotate.
+type GameRecorder struct {
+	session *Session
+	record  GameRecord
+	undone  []RecordedMove // moves taken back, the next one to redo last
+}
+
+// NewGameRecorder returns a recorder for s starting with the moves played so far, add it with s.AddListener
+func NewGameRecorder(s *Session) *GameRecorder {
+	return &GameRecorder{session: s, record: NewGameRecord(s)}
+}
+
+func (r *GameRecorder) HandleEvent(e Event) {
+	switch e := e.(type) {
+	case MoveMadeEvent:
+		r.record.Moves = append(r.record.Moves, RecordedMove{Move: e.Col})
+		r.undone = nil
+	case UndoEvent:
+		keep := len(r.record.Moves) - len(e.Moves)
+		for i := len(r.record.Moves) - 1; i >= keep; i-- {
+			r.undone = append(r.undone, r.record.Moves[i])
+		}
+		r.record.Moves = r.record.Moves[:keep]
+	case RedoEvent:
+		for _, m := range e.Moves {
+			move := RecordedMove{Move: m.Col}
+			if len(r.undone) > 0 {
+				move, r.undone = r.undone[len(r.undone)-1], r.undone[:len(r.undone)-1]
+			}
+			r.record.Moves = append(r.record.Moves, move)
+		}
+	}
+	r.record.SetTag("Result", r.session.resultTag())
+}
+
+// Annotate adds the search result of the engine that played the last move to it
+func (r *GameRecorder) Annotate(result SearchResult) {
+	if len(r.record.Moves) == 0 {
+		return
+	}
+	last := &r.record.Moves[len(r.record.Moves)-1]
+	last.Eval, last.Depth, last.HasEval = result.Score, result.Depth, true
+}
+
+// Record returns the record of the game so far
+func (r *GameRecorder) Record() GameRecord {
+	record := r.record
+	record.Tags = append([]Tag(nil), r.record.Tags...)
+	record.Moves = append([]RecordedMove(nil), r.record.Moves...)
+	return record
+}
@@ -0,0 +1,123 @@
+package connect4
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGameRecorder(t *testing.T) {
+	s := NewSession(Player{Name: "One", Piece: PlayerIcon}, Player{Name: "Two", Piece: CpuIcon, Engine: NewMiniMaxEngine(4)})
+	recorder := NewGameRecorder(s)
+	s.AddListener(recorder)
+
+	for i, move := range []Move{0, 1, 0, 1, 0, 1} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			recorder.Annotate(SearchResult{Score: float32(-i), Depth: 4})
+		}
+	}
+	// the evals come back with the moves that are redone
+	if err := s.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Redo(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Play(0); err != nil {
+		t.Fatal(err)
+	}
+
+	r := recorder.Record()
+	if r.Result() != ResultFirstWins || r.Tag("First") != "One" || r.Tag("SecondDepth") != "4" || r.Tag("Board") != "7x6" {
+		t.Errorf("tags = %v", r.Tags)
+	}
+	if len(r.Moves) != 7 || !r.Moves[5].HasEval || r.Moves[5].Eval != -5 || r.Moves[5].Depth != 4 || r.Moves[4].HasEval {
+		t.Errorf("moves = %+v", r.Moves)
+	}
+
+	want := "1. 1 2 {[%eval -1] [%depth 4]} 2. 1 2 {[%eval -3] [%depth 4]} 3. 1 2\n{[%eval -5] [%depth 4]} 4. 1 1-0\n"
+	if text := r.String(); !strings.HasSuffix(text, "]\n\n"+want) {
+		t.Errorf("String() =\n%s\nwant the moves\n%s", text, want)
+	}
+}
+
+func TestGameRecordRoundTrip(t *testing.T) {
+	start, err := NewPopOutBoard(10, 9, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSessionOn(start, Player{Name: `Ann "The Wall"`, Piece: PlayerIcon}, Player{Name: "Bob", Piece: CpuIcon})
+	for _, move := range []Move{9, 9, 3, 8, PopMove(9)} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := NewGameRecord(s)
+	first.Moves[1].Comment = "mirrors\nthe {first} move"
+	first.Moves[2] = RecordedMove{Move: 3, Eval: 0.25, HasEval: true}
+
+	second, err := NewBoard().PlayMoves("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewGameRecord(NewSessionOn(second, Player{Name: "C", Piece: CpuIcon}, Player{Name: "D", Piece: PlayerIcon}))
+
+	var sb strings.Builder
+	for _, r := range []GameRecord{first, other} {
+		if err := WriteRecord(&sb, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := ReadRecords(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("ReadRecords: %v\n%s", err, sb.String())
+	}
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+
+	first.Moves[1].Comment = "mirrors the (first) move"
+	if !reflect.DeepEqual(records[0], first) {
+		t.Errorf("read\n%+v\nwant\n%+v", records[0], first)
+	}
+	if records[1].Tag("Position") != second.Grid() {
+		t.Errorf("Position tag = %q, want %q", records[1].Tag("Position"), second.Grid())
+	}
+
+	final, err := records[0].Final()
+	if err != nil || final.Grid() != s.Board().Grid() {
+		t.Errorf("Final() = %q, %v, want %q", final.Grid(), err, s.Board().Grid())
+	}
+}
+
+func TestReadRecordsErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  error
+	}{
+		{`[Result "*"] 1. 4`, ErrInvalidRecord},
+		{`[Result "1-0"] 1. 4 *`, ErrInvalidRecord},
+		{`[Result] 1. 4 *`, ErrInvalidRecord},
+		{`{hello} 1. 4 *`, ErrInvalidRecord},
+		{`1. 4 {hello *`, ErrInvalidRecord},
+		{`1. 4 44 *`, ErrInvalidRecord},
+		{`[Variant "PopIn"] 1. 4 *`, ErrInvalidRecord},
+		{`[Board "3x3"] 1. 1 *`, ErrInvalidSize},
+		{`1. 8 *`, ErrColumnOutOfRange},
+		{`1. 4 p4 *`, ErrCannotPop},
+	}
+	for _, tt := range tests {
+		if _, err := ReadRecords(strings.NewReader(tt.text)); !errors.Is(err, tt.err) {
+			t.Errorf("ReadRecords(%q) error = %v, want %v", tt.text, err, tt.err)
+		}
+	}
+
+	if _, err := ParseRecord("1. 4 * 1. 4 *"); !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("ParseRecord of two games: error = %v, want ErrInvalidRecord", err)
+	}
+}
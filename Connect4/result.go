@@ -0,0 +1,104 @@
+package connect4
+
+import "fmt"
+
+// Outcome is the state of a game
+type Outcome uint8
+
+const (
+	InProgress Outcome = iota // moves can still be made
+	Win                       // a player has a line of the board's win length
+	Draw                      // the board is full and nobody has a winning line
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "Win"
+	case Draw:
+		return "Draw"
+	default:
+		return "In progress"
+	}
+}
+
+// Cell is a single square of the board, Row 0 is the bottom row
+type Cell struct {
+	Col uint `json:"col"`
+	Row uint `json:"row"`
+}
+
+// GameResult describes how a game stands
+type GameResult struct {
+	Outcome Outcome
+	Winner  Piece  // the winning piece, Empty unless Outcome is Win
+	Line    []Cell // the cells of the winning line from its bottom end, the left end of a line across
+}
+
+func (r GameResult) String() string {
+	if r.Outcome == Win {
+		return fmt.Sprintf("%v wins with %v", r.Winner, r.Line)
+	}
+	return r.Outcome.String()
+}
+
+// The directions a winning line can run in from its bottom-left end:
+// right, up, up and to the right, up and to the left
+var lineDirections = [][2]int{{1, 0}, {0, 1}, {1, 1}, {-1, 1}}
+
+// Result reports whether the game is still going, drawn or won and if so by whom and with which cells.
+// When the board holds a winning line for both players, which only a PopOut pop can do,
+// the player who moved last wins.
+func (board C4Board) Result() GameResult {
+	var result GameResult
+	for col := 0; col < int(board.numCols); col++ {
+		for row := 0; row < int(board.numRows); row++ {
+			piece := board.position[col][row]
+			if piece == Empty || (result.Outcome == Win && piece == result.Winner) {
+				continue
+			}
+			for _, dir := range lineDirections {
+				if line, ok := board.lineFrom(col, row, dir); ok {
+					result = GameResult{Outcome: Win, Winner: piece, Line: line}
+					break
+				}
+			}
+			if result.Outcome == Win && result.Winner == board.turn.Piece {
+				return result
+			}
+		}
+	}
+	if result.Outcome == Win {
+		return result
+	}
+
+	if len(board.LegalMoves()) == 0 || (board.popOut && board.repeated()) {
+		return GameResult{Outcome: Draw}
+	}
+	return GameResult{Outcome: InProgress}
+}
+
+// lineFrom returns the win length's worth of cells starting at (col, row) going in dir if they all hold the same piece
+func (board C4Board) lineFrom(col, row int, dir [2]int) ([]Cell, bool) {
+	piece := board.position[col][row]
+	line := make([]Cell, 0, board.winLength)
+	for i := 0; i < int(board.winLength); i++ {
+		c, r := col+i*dir[0], row+i*dir[1]
+		if c < 0 || c >= int(board.numCols) || r >= int(board.numRows) || board.position[c][r] != piece {
+			return nil, false
+		}
+		line = append(line, Cell{Col: uint(c), Row: uint(r)})
+	}
+	return line, true
+}
+
+// Terminal escape codes to show a cell in reverse video
+const (
+	highlightOn  = "\x1b[7m"
+	highlightOff = "\x1b[0m"
+)
+
+// StringHighlighted is String with the given cells highlighted, for showing the winning line
+func (board C4Board) StringHighlighted(cells []Cell) string {
+	return board.DrawHighlighted(Glyphs{}, cells)
+}
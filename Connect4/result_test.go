@@ -0,0 +1,117 @@
+package connect4
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestConnectN(t *testing.T) {
+	tests := []struct {
+		name      string
+		cols      uint
+		rows      uint
+		winLength uint
+		moves     []Move // alternating, PlayerIcon first
+		outcome   Outcome
+		line      int // how many cells the winning line has
+	}{
+		{"three across", 5, 4, 3, []Move{0, 0, 1, 1, 2}, Win, 3},
+		{"three up", 5, 4, 3, []Move{4, 3, 4, 3, 4}, Win, 3},
+		{"four is not five", 9, 6, 5, []Move{0, 0, 1, 1, 2, 2, 3, 3}, InProgress, 0},
+		{"five across", 9, 6, 5, []Move{0, 0, 1, 1, 2, 2, 3, 3, 4}, Win, 5},
+		{"five diagonal", 8, 7, 5, []Move{0, 1, 1, 2, 6, 2, 2, 3, 6, 3, 7, 3, 3, 4, 7, 4, 6, 4, 7, 4, 4}, Win, 5},
+	}
+	for _, tt := range tests {
+		b, err := NewConnectNBoard(tt.cols, tt.rows, tt.winLength)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		p := Player{Piece: PlayerIcon}
+		for _, move := range tt.moves {
+			if b, err = b.TryMove(p, move); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			p = p.Opponent()
+		}
+
+		result := b.Result()
+		if result.Outcome != tt.outcome || len(result.Line) != tt.line {
+			t.Errorf("%s: Result() = %v, want %v with a line of %d\n%s", tt.name, result, tt.outcome, tt.line, b)
+		}
+		if b.IsWin() != (tt.outcome == Win) {
+			t.Errorf("%s: IsWin() = %v\n%s", tt.name, b.IsWin(), b)
+		}
+		last := tt.moves[len(tt.moves)-1]
+		if b.lastMoveWins(last) != (tt.outcome == Win) {
+			t.Errorf("%s: lastMoveWins(%d) = %v\n%s", tt.name, last, b.lastMoveWins(last), b)
+		}
+	}
+}
+
+func TestConnectNLimits(t *testing.T) {
+	for _, n := range []uint{MinWinLength - 1, MaxCols + 1} {
+		if _, err := NewConnectNBoard(MaxCols, MaxRows, n); !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("NewConnectNBoard(%d, %d, %d) error = %v, want ErrInvalidSize", MaxCols, MaxRows, n, err)
+		}
+	}
+	// the line only has to fit along one side of the board
+	if _, err := NewConnectNBoard(7, 4, 7); err != nil {
+		t.Errorf("NewConnectNBoard(7, 4, 7) error = %v", err)
+	}
+}
+
+func TestResultLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		moves  string
+		winner Piece
+		line   []Cell
+	}{
+		{"up", "1212121", PlayerIcon, []Cell{{0, 0}, {0, 1}, {0, 2}, {0, 3}}},
+		{"across", "1122334", PlayerIcon, []Cell{{0, 0}, {1, 0}, {2, 0}, {3, 0}}},
+		{"across to the edge", "4455667", PlayerIcon, []Cell{{3, 0}, {4, 0}, {5, 0}, {6, 0}}},
+		{"up and right", "12233434464", PlayerIcon, []Cell{{0, 0}, {1, 1}, {2, 2}, {3, 3}}},
+		{"up and left", "76655454424", PlayerIcon, []Cell{{6, 0}, {5, 1}, {4, 2}, {3, 3}}},
+		{"second player", "17273757", CpuIcon, []Cell{{6, 0}, {6, 1}, {6, 2}, {6, 3}}},
+	}
+	for _, tt := range tests {
+		b, _ := playMoves(t, tt.moves)
+		result := b.Result()
+		if result.Outcome != Win || result.Winner != tt.winner || !slices.Equal(result.Line, tt.line) {
+			t.Errorf("%s: Result() = %v, want %v to win with %v\n%s", tt.name, result, tt.winner, tt.line, b)
+		}
+	}
+}
+
+func TestResultNoWinner(t *testing.T) {
+	b, _ := playMoves(t, "4453")
+	if result := b.Result(); result.Outcome != InProgress || result.Winner != Empty || result.Line != nil {
+		t.Errorf("4453: Result() = %+v, want a game in progress", result)
+	}
+
+	full, _ := playMoves(t, "544444433333355555222222666666111117777771")
+	if result := full.Result(); result.Outcome != Draw || result.Winner != Empty || result.Line != nil {
+		t.Errorf("full board: Result() = %+v, want a draw", result)
+	}
+}
+
+func TestLineFrom(t *testing.T) {
+	b, _ := playMoves(t, "1122334")
+	tests := []struct {
+		col, row int
+		dir      [2]int
+		ok       bool
+	}{
+		{0, 0, [2]int{1, 0}, true},
+		{1, 0, [2]int{1, 0}, false},  // the fourth cell to the right is empty
+		{0, 0, [2]int{0, 1}, false},  // the other player is on top
+		{3, 0, [2]int{-1, 1}, false}, // the next cell up and left is the other player's
+		{6, 0, [2]int{1, 0}, false},  // off the board
+	}
+	for _, tt := range tests {
+		if line, ok := b.lineFrom(tt.col, tt.row, tt.dir); ok != tt.ok || ok && len(line) != 4 {
+			t.Errorf("lineFrom(%d, %d, %v) = %v, %v, want %v", tt.col, tt.row, tt.dir, line, ok, tt.ok)
+		}
+	}
+}
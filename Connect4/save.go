@@ -0,0 +1,188 @@
+package connect4
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// ------------------------------------------------------
+// Saving and resuming games
+// ------------------------------------------------------
+// A game is saved as JSON with everything needed to carry on where it stopped: the board it is
+// played on, the players, the moves in the move sequence notation and the settings the game was
+// started with. Nothing from the board itself is stored, on loading the moves are replayed on a
+// fresh board so a file that was edited by hand or is from a different version can't sneak in
+// a position the rules don't allow.
+
+// SavedGame is a game as it is written to a save file
+type SavedGame struct {
+	Board    SavedBoard     `json:"board"`
+	Players  [2]SavedPlayer `json:"players"` // first mover first
+	ToMove   int            `json:"toMove"`  // index in Players of who moves next
+	Moves    []SavedMove    `json:"moves"`
+	Settings GameSettings   `json:"settings"`
+	SavedAt  time.Time      `json:"savedAt"`
+}
+
+// SavedBoard is the kind of board a saved game is played on, it starts empty
+type SavedBoard struct {
+	Cols      uint `json:"cols"`
+	Rows      uint `json:"rows"`
+	WinLength uint `json:"winLength"`
+	PopOut    bool `json:"popOut,omitempty"`
+}
+
+// SavedPlayer is a player of a saved game
+type SavedPlayer struct {
+	Name    string `json:"name"`
+	Piece   Piece  `json:"piece"`
+	IsHuman bool   `json:"isHuman"`
+	Engine  string `json:"engine,omitempty"` // the engine's Name, for people reading the file
+}
+
+// SavedMove is one move of a saved game
+type SavedMove struct {
+	Move Move      `json:"move"`
+	Time time.Time `json:"time"`
+}
+
+// GameSettings are the choices made when a game was set up that aren't part of the board or the players
+type GameSettings struct {
+	Opponents    [2]int  `json:"opponents"`        // key in the opponents menu of the computer in each seat, 0 for a human
+	Depths       [2]uint `json:"depths,omitempty"` // search depth of the computer in each seat, 0 for the engine's own
+	ShowAnalysis bool    `json:"showAnalysis,omitempty"`
+
+	// Opponent is the computer in the second seat in games saved before either seat could be
+	// a computer, against a human in the first seat
+	Opponent int `json:"opponent,omitempty"`
+}
+
+// SeatOpponent returns the key in the opponents menu of the computer in seat 0 or 1, 0 for a human
+func (s GameSettings) SeatOpponent(seat int) int {
+	if s.Opponents == [2]int{} && seat == 1 {
+		return s.Opponent
+	}
+	return s.Opponents[seat]
+}
+
+// NewSavedGame returns the game being played in s, ready to be written out
+func NewSavedGame(s *Session, settings GameSettings) SavedGame {
+	start := s.start
+	game := SavedGame{
+		Board: SavedBoard{
+			Cols:      start.Cols(),
+			Rows:      start.Rows(),
+			WinLength: start.WinLength(),
+			PopOut:    start.IsPopOut(),
+		},
+		ToMove:   s.next,
+		Moves:    make([]SavedMove, 0, len(s.history)),
+		Settings: settings,
+		SavedAt:  time.Now(),
+	}
+	for i, p := range s.players {
+		game.Players[i] = SavedPlayer{Name: p.Name, Piece: p.Piece, IsHuman: p.IsHuman}
+		if p.Engine != nil {
+			game.Players[i].Engine = p.Engine.Name()
+		}
+	}
+	for _, record := range s.history {
+		game.Moves = append(game.Moves, SavedMove{Move: record.Col, Time: record.Time})
+	}
+	return game
+}
+
+// Resume starts a session that carries on the saved game. The players are given back their
+// TurnCount counters and engines by the caller, they are only matched up by piece.
+// Returns ErrInvalidSave if the file doesn't describe a game that can be played,
+// or the error of the first stored move that isn't legal on a fresh board.
+func (g SavedGame) Resume(first, second Player) (*Session, error) {
+	newBoard := NewConnectNBoard
+	if g.Board.PopOut {
+		newBoard = NewPopOutBoard
+	}
+	start, err := newBoard(g.Board.Cols, g.Board.Rows, g.Board.WinLength)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSave, err)
+	}
+
+	for i, p := range [2]Player{first, second} {
+		if g.Players[i].Piece != p.Piece {
+			return nil, fmt.Errorf("%w: player %d plays %q, not %q", ErrInvalidSave, i+1, g.Players[i].Piece, p.Piece)
+		}
+	}
+	if first.Piece == Empty || first.Piece.opposite() != second.Piece {
+		return nil, fmt.Errorf("%w: players with pieces %q and %q", ErrInvalidSave, first.Piece, second.Piece)
+	}
+	if g.ToMove != len(g.Moves)%2 {
+		return nil, fmt.Errorf("%w: player %d to move after %d moves", ErrInvalidSave, g.ToMove+1, len(g.Moves))
+	}
+
+	s := NewSessionOn(start, first, second)
+	history := make([]MoveRecord, 0, len(g.Moves))
+	for i, saved := range g.Moves {
+		history = append(history, MoveRecord{Player: s.players[i%2], Col: saved.Move, Time: saved.Time})
+	}
+	board, err := BoardFromHistory(start, history)
+	if err != nil {
+		return nil, err
+	}
+
+	s.board = board
+	s.history = history
+	s.next = g.ToMove
+	return s, nil
+}
+
+// WriteFile saves the game to the file at path, replacing what was there
+func (g SavedGame) WriteFile(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadGame reads a game saved by WriteFile. Returns an error wrapping fs.ErrNotExist if there is no file
+// and ErrInvalidSave if it isn't a saved game, the moves are only checked by Resume.
+func LoadGame(path string) (SavedGame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SavedGame{}, err
+	}
+	var g SavedGame
+	if err := json.Unmarshal(data, &g); err != nil {
+		return SavedGame{}, fmt.Errorf("%w: %s: %w", ErrInvalidSave, path, err)
+	}
+	return g, nil
+}
+
+// RemoveSave deletes the save file at path, it is not an error if there isn't one
+func RemoveSave(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SaveListener writes the game to a file after every move, undo and redo so it can be resumed
+// if the program is closed. Errors are reported to OnError if it is set.
+type SaveListener struct {
+	Path     string
+	Session  *Session
+	Settings GameSettings
+	OnError  func(err error)
+}
+
+func (l SaveListener) HandleEvent(e Event) {
+	switch e.(type) {
+	case MoveMadeEvent, UndoEvent, RedoEvent:
+		if err := NewSavedGame(l.Session, l.Settings).WriteFile(l.Path); err != nil && l.OnError != nil {
+			l.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package connect4
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndResume(t *testing.T) {
+	start, err := NewPopOutBoard(8, 7, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSessionOn(start, Player{Name: "One", Piece: PlayerIcon}, Player{Name: "Two", Piece: CpuIcon})
+	path := filepath.Join(t.TempDir(), "save.json")
+	s.AddListener(SaveListener{Path: path, Session: s, Settings: GameSettings{Opponents: [2]int{0, 3}},
+		OnError: func(err error) { t.Error(err) }})
+
+	for _, move := range []Move{3, 3, 4, 7, PopMove(3)} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved, err := LoadGame(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Settings.SeatOpponent(1) != 3 || saved.Players[1].Name != "Two" || len(saved.Moves) != 5 || saved.Moves[4].Move != PopMove(3) {
+		t.Errorf("LoadGame = %+v", saved)
+	}
+
+	resumed, err := saved.Resume(s.Players()[0], s.Players()[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Board().Grid() != s.Board().Grid() || resumed.Board().Hash() != s.Board().Hash() {
+		t.Errorf("resumed board\n%s want\n%s", resumed.Board(), s.Board())
+	}
+	if resumed.ToMove().Name != "Two" || len(resumed.History()) != 5 || !resumed.History()[0].Time.Equal(s.History()[0].Time) {
+		t.Errorf("resumed %s to move after %v", resumed.ToMove().Name, resumed.History())
+	}
+
+	// the resumed game can be taken back to the start
+	if err := resumed.Undo(5); err != nil || resumed.Board().Grid() != start.Grid() {
+		t.Errorf("Undo(5) on the resumed game: %v\n%s", err, resumed.Board())
+	}
+
+	if err := RemoveSave(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGame(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadGame after RemoveSave: error = %v, want fs.ErrNotExist", err)
+	}
+	if err := RemoveSave(path); err != nil {
+		t.Errorf("RemoveSave without a file: %v", err)
+	}
+}
+
+func TestResumeRejectsBadSaves(t *testing.T) {
+	p1, p2 := Player{Piece: PlayerIcon}, Player{Piece: CpuIcon}
+	good := SavedGame{
+		Board:   SavedBoard{Cols: NumCols, Rows: NumRows, WinLength: WinLength},
+		Players: [2]SavedPlayer{{Piece: PlayerIcon}, {Piece: CpuIcon}},
+		ToMove:  1,
+		Moves:   []SavedMove{{Move: 3}},
+	}
+	if _, err := good.Resume(p1, p2); err != nil {
+		t.Fatalf("Resume of a good save: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		edit func(g *SavedGame)
+		err  error
+	}{
+		{"board too small", func(g *SavedGame) { g.Board.Cols = 2 }, ErrInvalidSave},
+		{"wrong pieces", func(g *SavedGame) { g.Players[0].Piece = CpuIcon }, ErrInvalidSave},
+		{"wrong player to move", func(g *SavedGame) { g.ToMove = 0 }, ErrInvalidSave},
+		{"off the board", func(g *SavedGame) { g.Moves[0].Move = 8 }, ErrColumnOutOfRange},
+		{"pop without PopOut", func(g *SavedGame) { g.Moves = append(g.Moves, SavedMove{Move: PopMove(3)}); g.ToMove = 0 }, ErrCannotPop},
+	}
+	for _, tt := range tests {
+		g := good
+		g.Moves = append([]SavedMove(nil), good.Moves...)
+		tt.edit(&g)
+		if _, err := g.Resume(p1, p2); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "save.json")
+	for _, broken := range []string{"not json", `{"moves": [{"move": "44"}]}`} {
+		if err := os.WriteFile(path, []byte(broken), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGame(path); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("LoadGame of %q: error = %v, want ErrInvalidSave", broken, err)
+		}
+	}
+}
+
+func TestResumeSavedSeats(t *testing.T) {
+	tests := []struct {
+		name      string
+		save      string
+		opponents [2]int // SeatOpponent of each seat
+		depths    [2]uint
+		toMove    string
+	}{
+		{
+			// saved before either seat could be a computer, the computer is always second
+			"legacy", `{
+				"board": {"cols": 7, "rows": 6, "winLength": 4},
+				"players": [{"name": "Ann", "piece": "+", "isHuman": true}, {"name": "Bot", "piece": "*", "isHuman": false, "engine": "Minimax (depth 4)"}],
+				"toMove": 0,
+				"moves": [{"move": "4", "time": "2024-05-01T10:00:00Z"}, {"move": "3", "time": "2024-05-01T10:00:05Z"}],
+				"settings": {"opponent": 3},
+				"savedAt": "2024-05-01T10:00:06Z"
+			}`, [2]int{0, 3}, [2]uint{0, 4}, "Ann",
+		},
+		{
+			"computer first", `{
+				"board": {"cols": 7, "rows": 6, "winLength": 4},
+				"players": [{"name": "Bot", "piece": "+", "isHuman": false}, {"name": "Ann", "piece": "*", "isHuman": true}],
+				"toMove": 1,
+				"moves": [{"move": "4", "time": "2024-05-01T10:00:00Z"}],
+				"settings": {"opponents": [3, 0], "depths": [2, 0]},
+				"savedAt": "2024-05-01T10:00:01Z"
+			}`, [2]int{3, 0}, [2]uint{2, 0}, "Ann",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "save.json")
+		if err := os.WriteFile(path, []byte(tt.save), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := LoadGame(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		s, err := resumeSaved(saved)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		for seat, p := range s.Players() {
+			if got := saved.Settings.SeatOpponent(seat); got != tt.opponents[seat] {
+				t.Errorf("%s: SeatOpponent(%d) = %d, want %d", tt.name, seat, got, tt.opponents[seat])
+			}
+			if p.Name != saved.Players[seat].Name || p.Piece != seatPieces[seat] || p.TurnCount == nil {
+				t.Errorf("%s: seat %d player %+v", tt.name, seat, p)
+			}
+			if tt.opponents[seat] == 0 {
+				if !p.IsHuman || p.Engine != nil {
+					t.Errorf("%s: seat %d is %+v, want a human", tt.name, seat, p)
+				}
+			} else if e, ok := p.Engine.(*MiniMaxEngine); p.IsHuman || !ok || e.Depth != tt.depths[seat] {
+				t.Errorf("%s: seat %d engine %#v, want minimax at depth %d", tt.name, seat, p.Engine, tt.depths[seat])
+			}
+		}
+		if s.ToMove().Name != tt.toMove || len(s.History()) != len(saved.Moves) {
+			t.Errorf("%s: %s to move after %d moves", tt.name, s.ToMove().Name, len(s.History()))
+		}
+	}
+
+	unknown := SavedGame{
+		Board:    SavedBoard{Cols: NumCols, Rows: NumRows, WinLength: WinLength},
+		Players:  [2]SavedPlayer{{Name: "Bot", Piece: PlayerIcon}, {Name: "Ann", Piece: CpuIcon, IsHuman: true}},
+		Settings: GameSettings{Opponents: [2]int{99, 0}},
+	}
+	if _, err := resumeSaved(unknown); !errors.Is(err, ErrInvalidSave) {
+		t.Errorf("engine 99: error = %v, want ErrInvalidSave", err)
+	}
+}
@@ -0,0 +1,61 @@
+package connect4
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchResult is what a search found out about a position
+type SearchResult struct {
+	Move    Move          // the move to play
+	Score   float32       // Evaluate score of Move for the searching player at Depth
+	PV      []Move        // principal variation, the line of play the search expects starting with Move
+	Nodes   uint64        // positions searched, across every iteration and goroutine
+	Depth   uint          // how many plies past Move the deepest finished iteration looked
+	Elapsed time.Duration // how long the search took
+}
+
+// NodesPerSecond is the search speed
+func (r SearchResult) NodesPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Nodes) / r.Elapsed.Seconds()
+}
+
+// String describes the result on a single line, for printing after a move
+func (r SearchResult) String() string {
+	var pv strings.Builder
+	for i, move := range r.PV {
+		if i > 0 {
+			pv.WriteString(" ")
+		}
+		fmt.Fprintf(&pv, "%v", move)
+	}
+
+	return fmt.Sprintf("move %v score %.0f depth %d nodes %d time %v (%.0f nodes/s) pv %s",
+		r.Move, r.Score, r.Depth, r.Nodes, r.Elapsed.Round(time.Millisecond), r.NodesPerSecond(), pv.String())
+}
+
+// principalVariation follows the best moves stored in tt from the position after first,
+// which p played and which was searched depth plies deep.
+// Only exact scores are followed, the move stored with a bound is just the one that caused a cutoff,
+// so the line ends early at the first position whose entry is a bound or that the table no longer holds.
+func principalVariation(b C4Board, p Player, first Move, depth uint, tt *TranspositionTable) []Move {
+	pv := []Move{first}
+
+	b = b.MakeMove(p, first)
+	mover := p.Opponent()
+	for ; depth > 0 && !b.IsGameOver(); depth-- {
+		entry, ok := tt.lookup(b.searchKey(mover.Piece, p.Piece))
+		if !ok || entry.Bound != ExactBound || !b.determineIfLegalMove(entry.Move) {
+			break
+		}
+		pv = append(pv, entry.Move)
+		b = b.MakeMove(mover, entry.Move)
+		mover = mover.Opponent()
+	}
+
+	return pv
+}
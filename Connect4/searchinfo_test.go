@@ -0,0 +1,67 @@
+package connect4
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPrincipalVariationForcedWin(t *testing.T) {
+	// the first player makes an open three along the bottom row, one end is blocked and the other wins
+	b, p := playMoves(t, "4455")
+	searches := map[string]func() SearchResult{
+		"MiniMaxEngine": func() SearchResult { return NewMiniMaxEngine(2).Search(b, p) },
+		"Search": func() SearchResult {
+			return Search(context.Background(), b, p, 2, NewTranspositionTable(DefaultTableSize))
+		},
+		"ConcurrentSearch": func() SearchResult {
+			return ConcurrentSearch(context.Background(), b, p, 2, NewTranspositionTable(DefaultTableSize))
+		},
+	}
+	for name, search := range searches {
+		result := search()
+		if len(result.PV) != 3 || result.PV[0] != result.Move {
+			t.Errorf("%s: move %v with pv %v, want the three moves of the win", name, result.Move, result.PV)
+			continue
+		}
+
+		line, mover := b, p
+		for i, move := range result.PV {
+			if !line.determineIfLegalMove(move) {
+				t.Fatalf("%s: pv %v plays the illegal move %v", name, result.PV, move)
+			}
+			line = line.MakeMove(mover, move)
+			mover = mover.Opponent()
+			if over := line.IsGameOver(); over != (i == len(result.PV)-1) {
+				t.Errorf("%s: pv %v, game over %v after move %d", name, result.PV, over, i+1)
+			}
+		}
+		// p plays the last move of the pv, so a win is p's
+		if !line.IsWin() {
+			t.Errorf("%s: pv %v doesn't end in a win for %v", name, result.PV, p.Piece)
+		}
+	}
+}
+
+func TestPrincipalVariationStopsAtBounds(t *testing.T) {
+	b, p := playMoves(t, "44")
+	after := b.MakeMove(p, 2)
+	key := after.searchKey(p.Opponent().Piece, p.Piece)
+	next := after.MakeMove(p.Opponent(), 3).searchKey(p.Piece, p.Piece)
+
+	for _, tc := range []struct {
+		bound Bound
+		want  []Move
+	}{
+		{ExactBound, []Move{2, 3, 4}},
+		{LowerBound, []Move{2}},
+		{UpperBound, []Move{2}},
+	} {
+		tt := NewTranspositionTable(1024)
+		tt.Store(key, 2, 0, tc.bound, 3)
+		tt.Store(next, 1, 0, ExactBound, 4)
+		if pv := principalVariation(b, p, 2, 2, tt); !slices.Equal(pv, tc.want) {
+			t.Errorf("bound %d: pv %v, want %v", tc.bound, pv, tc.want)
+		}
+	}
+}
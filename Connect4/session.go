@@ -0,0 +1,177 @@
+package connect4
+
+import "time"
+
+// ------------------------------------------------------
+// Game session and events
+// ------------------------------------------------------
+// The board only knows the rules, a Session runs a game on it: it keeps the players and
+// the moves they played, knows whose turn it is and tells its listeners about everything that happens.
+// Anything that wants to show or record a game (the console, a GUI, a server) is a Listener.
+
+// Event is something that happened in a session, one of MoveMadeEvent, WinnerEvent, DrawEvent,
+// GameOverEvent, UndoEvent or RedoEvent
+type Event interface {
+	isEvent()
+}
+
+// MoveMadeEvent is sent after every move
+type MoveMadeEvent struct {
+	Player Player
+	Col    Move
+	Board  C4Board // the board after the move
+}
+
+// WinnerEvent is sent when a move wins the game
+type WinnerEvent struct {
+	Winner Player
+	Line   []Cell // the winning cells
+	Board  C4Board
+}
+
+// DrawEvent is sent when a move fills the board without a winner
+type DrawEvent struct {
+	Board C4Board
+}
+
+// GameOverEvent is sent last when the game ends, whichever way it ended
+type GameOverEvent struct {
+	Result GameResult
+	Board  C4Board
+}
+
+// UndoEvent is sent when moves are taken back
+type UndoEvent struct {
+	Moves []MoveRecord // the moves taken back, oldest first
+	Board C4Board      // the board without them
+}
+
+// RedoEvent is sent when moves that were taken back are played again,
+// followed by WinnerEvent or DrawEvent and GameOverEvent if they end the game
+type RedoEvent struct {
+	Moves []MoveRecord // the moves played again, oldest first
+	Board C4Board      // the board with them
+}
+
+func (MoveMadeEvent) isEvent() {}
+func (WinnerEvent) isEvent()   {}
+func (DrawEvent) isEvent()     {}
+func (GameOverEvent) isEvent() {}
+func (UndoEvent) isEvent()     {}
+func (RedoEvent) isEvent()     {}
+
+// Listener is told about the events of a session
+type Listener interface {
+	HandleEvent(e Event)
+}
+
+// ListenerFunc lets an ordinary function be used as a Listener
+type ListenerFunc func(e Event)
+
+func (f ListenerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// Session is a game between two players
+type Session struct {
+	board     C4Board
+	players   [2]Player
+	next      int // index in players of who moves next
+	listeners []Listener
+
+	start   C4Board      // the board the game started on
+	history []MoveRecord // every move played, oldest first
+	undone  []MoveRecord // moves taken back that Redo can play again, the next one last
+}
+
+// NewSession starts a game on an empty board, first makes the first move
+func NewSession(first, second Player) *Session {
+	return NewSessionOn(NewBoard(), first, second)
+}
+
+// NewSessionOn starts a game on board, which is usually an empty board of some other size,
+// first makes the next move
+func NewSessionOn(board C4Board, first, second Player) *Session {
+	return &Session{board: board, start: board, players: [2]Player{first, second}}
+}
+
+// AddListener registers l to be told about every event from now on
+func (s *Session) AddListener(l Listener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// Board returns the current board
+func (s *Session) Board() C4Board {
+	return s.board
+}
+
+// Players returns both players, first mover first
+func (s *Session) Players() [2]Player {
+	return s.players
+}
+
+// ToMove returns the player whose turn it is
+func (s *Session) ToMove() Player {
+	return s.players[s.next]
+}
+
+// Result returns how the game stands
+func (s *Session) Result() GameResult {
+	return s.board.Result()
+}
+
+// IsOver reports whether the game has been won or drawn
+func (s *Session) IsOver() bool {
+	return s.board.IsGameOver()
+}
+
+// Play makes the move of the player whose turn it is in column col and sends the events for it.
+// An illegal move returns the error from TryMove and changes nothing.
+// Playing a move forgets the moves waiting to be redone.
+func (s *Session) Play(col Move) error {
+	p := s.ToMove()
+	board, err := s.board.TryMove(p, col)
+	if err != nil {
+		return err
+	}
+
+	s.board = board
+	s.next = 1 - s.next
+	s.history = append(s.history, MoveRecord{Player: p, Col: col, Time: time.Now()})
+	s.undone = nil
+	if p.TurnCount != nil {
+		p.TurnCount()
+	}
+	s.emit(MoveMadeEvent{Player: p, Col: col, Board: board})
+	s.emitResult(board)
+
+	return nil
+}
+
+// emitResult sends the events for the end of the game if board is the end of it
+func (s *Session) emitResult(board C4Board) {
+	result := board.Result()
+	switch result.Outcome {
+	case Win:
+		s.emit(WinnerEvent{Winner: s.playerWithPiece(result.Winner), Line: result.Line, Board: board})
+	case Draw:
+		s.emit(DrawEvent{Board: board})
+	}
+	if result.Outcome != InProgress {
+		s.emit(GameOverEvent{Result: result, Board: board})
+	}
+}
+
+// playerWithPiece returns the player playing piece
+func (s *Session) playerWithPiece(piece Piece) Player {
+	if s.players[1].Piece == piece {
+		return s.players[1]
+	}
+	return s.players[0]
+}
+
+func (s *Session) emit(e Event) {
+	for _, l := range s.listeners {
+		l.HandleEvent(e)
+	}
+}
@@ -0,0 +1,227 @@
+package connect4
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestSession() *Session {
+	return NewSession(Player{Name: "One", Piece: PlayerIcon}, Player{Name: "Two", Piece: CpuIcon})
+}
+
+func TestSessionHistory(t *testing.T) {
+	s := newTestSession()
+	moves := []Move{3, 3, 4, 2}
+	for _, move := range moves {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := s.History()
+	if len(history) != len(moves) {
+		t.Fatalf("History() has %d moves, want %d", len(history), len(moves))
+	}
+	for i, record := range history {
+		if record.Col != moves[i] || record.Player.Name != s.Players()[i%2].Name || record.Time.IsZero() {
+			t.Errorf("History()[%d] = %+v, want %v by %s", i, record, moves[i], s.Players()[i%2].Name)
+		}
+	}
+
+	board, err := BoardFromHistory(NewBoard(), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.String() != s.Board().String() || board.Hash() != s.Board().Hash() {
+		t.Errorf("rebuilt board\n%s want\n%s", board, s.Board())
+	}
+
+	history[1].Col = 9
+	if _, err := BoardFromHistory(NewBoard(), history); !errors.Is(err, ErrColumnOutOfRange) {
+		t.Errorf("BoardFromHistory with a bad move: error = %v, want ErrColumnOutOfRange", err)
+	}
+}
+
+func TestSessionUndoRedo(t *testing.T) {
+	s := newTestSession()
+	var events []Event
+	s.AddListener(ListenerFunc(func(e Event) { events = append(events, e) }))
+
+	for _, move := range []Move{3, 3, 4, 2, 5} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := s.Board()
+
+	if err := s.Undo(3); err != nil {
+		t.Fatal(err)
+	}
+	want := NewBoard().MakeMove(Player{Piece: PlayerIcon}, 3).MakeMove(Player{Piece: CpuIcon}, 3)
+	if s.Board().String() != want.String() || s.Board().Hash() != want.Hash() {
+		t.Errorf("after Undo(3)\n%s want\n%s", s.Board(), want)
+	}
+	if s.ToMove().Name != "One" || len(s.History()) != 2 || len(s.RedoMoves()) != 3 {
+		t.Errorf("after Undo(3) %s to move with %d moves played and %d to redo",
+			s.ToMove().Name, len(s.History()), len(s.RedoMoves()))
+	}
+	if e, ok := events[len(events)-1].(UndoEvent); !ok || len(e.Moves) != 3 || e.Moves[0].Col != 4 {
+		t.Errorf("last event = %#v, want an UndoEvent for 4 2 5", events[len(events)-1])
+	}
+
+	if err := s.Undo(3); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo(3) with 2 moves played: error = %v, want ErrNothingToUndo", err)
+	}
+
+	if err := s.Redo(3); err != nil {
+		t.Fatal(err)
+	}
+	if s.Board().String() != after.String() || s.ToMove().Name != "Two" {
+		t.Errorf("after Redo(3)\n%s want\n%s", s.Board(), after)
+	}
+	if err := s.Redo(1); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo(1) with nothing undone: error = %v, want ErrNothingToRedo", err)
+	}
+
+	// a new move forgets what was undone
+	if err := s.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Play(0); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.RedoMoves()) != 0 {
+		t.Errorf("RedoMoves() = %v after a new move", s.RedoMoves())
+	}
+}
+
+func TestSessionUndoWin(t *testing.T) {
+	s := newTestSession()
+	for _, move := range []Move{0, 1, 0, 1, 0, 1, 0} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.IsOver() {
+		t.Fatal("game not over after four in column 0")
+	}
+
+	if err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if s.IsOver() {
+		t.Error("game still over after taking back the winning move")
+	}
+
+	var over bool
+	s.AddListener(ListenerFunc(func(e Event) {
+		if _, ok := e.(GameOverEvent); ok {
+			over = true
+		}
+	}))
+	if err := s.Redo(1); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsOver() || !over {
+		t.Error("redoing the winning move didn't end the game")
+	}
+}
+
+// eventNames records the kind of every event a session sends
+func eventNames(s *Session) *[]string {
+	var names []string
+	s.AddListener(ListenerFunc(func(e Event) {
+		switch e.(type) {
+		case MoveMadeEvent:
+			names = append(names, "MoveMade")
+		case WinnerEvent:
+			names = append(names, "Winner")
+		case DrawEvent:
+			names = append(names, "Draw")
+		case GameOverEvent:
+			names = append(names, "GameOver")
+		case UndoEvent:
+			names = append(names, "Undo")
+		case RedoEvent:
+			names = append(names, "Redo")
+		}
+	}))
+	return &names
+}
+
+func TestSessionEventOrder(t *testing.T) {
+	s := newTestSession()
+	names := eventNames(s)
+	var winner WinnerEvent
+	var over GameOverEvent
+	s.AddListener(ListenerFunc(func(e Event) {
+		switch e := e.(type) {
+		case WinnerEvent:
+			winner = e
+		case GameOverEvent:
+			over = e
+		}
+	}))
+
+	for _, move := range []Move{0, 1, 0, 1, 0, 1} {
+		if err := s.Play(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Play(9); err == nil {
+		t.Fatal("Play(9) succeeded")
+	}
+	if len(*names) != 6 {
+		t.Errorf("events before the win %v, want a MoveMade for each move", *names)
+	}
+
+	*names = nil
+	if err := s.Play(0); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(*names, " "); got != "MoveMade Winner GameOver" {
+		t.Errorf("winning move sent %s, want MoveMade Winner GameOver", got)
+	}
+	if winner.Winner.Name != "One" || len(winner.Line) != 4 || over.Result.Winner != PlayerIcon {
+		t.Errorf("winner %+v and game over %+v, want One to win", winner, over)
+	}
+
+	*names = nil
+	if err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Redo(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(*names, " "); got != "Undo Redo Winner GameOver" {
+		t.Errorf("taking back and redoing the win sent %s, want Undo Redo Winner GameOver", got)
+	}
+
+	// fill the board without a line, the last move draws
+	s = newTestSession()
+	names = eventNames(s)
+	draw := "544444433333355555222222666666111117777771"
+	for _, c := range draw {
+		*names = nil
+		if err := s.Play(Move(c - '1')); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(*names, " "); got != "MoveMade Draw GameOver" {
+		t.Errorf("drawing move sent %s, want MoveMade Draw GameOver", got)
+	}
+}
+
+func TestMakePlayerMove(t *testing.T) {
+	b, p := playMoves(t, "444444")
+	withStdin(t, "x\n3\n9\n2\n")
+	if got, want := b.MakePlayerMove(p), b.MakeMove(p, 2); got.String() != want.String() {
+		t.Errorf("after a word, a full column and a column off the board\n%s want\n%s", got, want)
+	}
+
+	// nothing left to read
+	if got := b.MakePlayerMove(p); got.String() != b.String() {
+		t.Errorf("MakePlayerMove at the end of the input\n%s want it unchanged", got)
+	}
+}
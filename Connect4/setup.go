@@ -0,0 +1,407 @@
+package connect4
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------
+// Pre-game setup
+// ------------------------------------------------------
+// Before a game the player picks the opponent by difficulty (or an engine of their own choosing, another person
+// or two engines to watch play each other), who moves first, the names and the glyphs the pieces are drawn with.
+// The choices are remembered in a file for the next game and can be given on the command line,
+// anything given there isn't asked for.
+
+// The file the last setup is remembered in, in the directory the program is run from
+const setupFile = "connect4-setup.json"
+
+// Difficulty is a level of computer opponent, an engine from the opponents menu at a set depth
+type Difficulty struct {
+	Name        string
+	Description string
+	Opponent    int  // key in the opponents menu
+	Depth       uint // search depth, 0 for the engine's own
+}
+
+// The difficulties to choose from, easiest first
+var difficulties = []Difficulty{
+	{Name: "easy", Description: "looks one move ahead", Opponent: 2},
+	{Name: "medium", Description: "minimax 4 moves ahead", Opponent: 3, Depth: 4},
+	{Name: "hard", Description: "searches up to 8 moves ahead for 2 seconds", Opponent: 4, Depth: cpuSearchDepth},
+	{Name: "expert", Description: "perfect play once the board fills up", Opponent: 5, Depth: cpuSearchDepth},
+}
+
+// The choices of opponent that aren't a difficulty
+const (
+	customDifficulty   = "custom"   // an engine from the opponents menu
+	humanDifficulty    = "human"    // another person at the same terminal
+	computerDifficulty = "computer" // two engines from the opponents menu playing each other
+)
+
+// GameSetup is what is chosen before a game starts
+type GameSetup struct {
+	Difficulty     string    `json:"difficulty"`               // a difficulty's name, "custom", "human" or "computer"
+	Opponent       int       `json:"opponent,omitempty"`       // the custom or first computer's key in the opponents menu
+	Depth          uint      `json:"depth,omitempty"`          // the custom or first computer's search depth, 0 for its own
+	SecondOpponent int       `json:"secondOpponent,omitempty"` // the second computer's key in the opponents menu
+	SecondDepth    uint      `json:"secondDepth,omitempty"`    // the second computer's search depth, 0 for its own
+	HumanFirst     bool      `json:"humanFirst"`               // the human moves first, with two humans or computers the first name does
+	Names          [2]string `json:"names"`                    // the human's and the computer's name, or both humans' or computers'
+	Glyphs         string    `json:"glyphs"`                   // the first and second player's glyphs
+}
+
+// defaultSetup is the setup of the first game, before there is one to remember
+var defaultSetup = GameSetup{
+	Difficulty: "medium",
+	HumanFirst: true,
+	Names:      [2]string{"Player", "Computer"},
+	Glyphs:     DefaultGlyphs,
+}
+
+// engine returns the key in the opponents menu and the depth of the computer opponent, 0 for a human one,
+// or of the first computer when two play each other. Returns false for a difficulty that doesn't exist.
+func (setup GameSetup) engine() (int, uint, bool) {
+	switch setup.Difficulty {
+	case humanDifficulty:
+		return 0, 0, true
+	case customDifficulty, computerDifficulty:
+		_, ok := opponents[setup.Opponent]
+		return setup.Opponent, setup.Depth, ok
+	}
+	for _, d := range difficulties {
+		if d.Name == setup.Difficulty {
+			return d.Opponent, d.Depth, true
+		}
+	}
+	return 0, 0, false
+}
+
+// seat is who plays in one of the two seats of a game
+type seat struct {
+	name     string
+	opponent int  // key in the opponents menu, 0 for a human
+	depth    uint // search depth, 0 for the engine's own
+}
+
+// seats returns who plays first and second. Returns false for a difficulty or engine that doesn't exist.
+func (setup GameSetup) seats() ([2]seat, bool) {
+	opponent, depth, ok := setup.engine()
+	if setup.Difficulty == computerDifficulty {
+		_, secondOK := opponents[setup.SecondOpponent]
+		return [2]seat{
+			{setup.Names[0], opponent, depth},
+			{setup.Names[1], setup.SecondOpponent, setup.SecondDepth},
+		}, ok && secondOK
+	}
+	human, computer := seat{name: setup.Names[0]}, seat{setup.Names[1], opponent, depth}
+	if !setup.HumanFirst {
+		return [2]seat{computer, human}, ok
+	}
+	return [2]seat{human, computer}, ok
+}
+
+// glyphs returns the glyphs of Glyphs, the first player's first.
+// Returns ErrInvalidPiece if they can't be drawn.
+func (setup GameSetup) glyphs() (Glyphs, error) {
+	if utf8.RuneCountInString(setup.Glyphs) != 2 {
+		return Glyphs{}, fmt.Errorf("%w: %q isn't two glyphs", ErrInvalidPiece, setup.Glyphs)
+	}
+	first, size := utf8.DecodeRuneInString(setup.Glyphs)
+	return NewGlyphs(string(first), setup.Glyphs[size:])
+}
+
+// check returns an error if the setup can't be played
+func (setup GameSetup) check() error {
+	seats, ok := setup.seats()
+	if !ok {
+		return fmt.Errorf("no %q difficulty or engine %d or %d", setup.Difficulty, setup.Opponent, setup.SecondOpponent)
+	}
+	for _, s := range seats {
+		if s.depth > maxEngineDepth {
+			return fmt.Errorf("depth %d is deeper than %d", s.depth, maxEngineDepth)
+		}
+	}
+	if setup.Names[0] == "" || setup.Names[1] == "" {
+		return errors.New("both players need a name")
+	}
+	_, err := setup.glyphs()
+	return err
+}
+
+// players returns the two players the setup makes, the first mover first, and the settings saved with the game
+func (setup GameSetup) players() ([2]Player, GameSettings) {
+	seats, _ := setup.seats()
+	var players [2]Player
+	var settings GameSettings
+	for i, s := range seats {
+		players[i] = newPlayer(i, s.name, s.opponent, s.depth)
+		settings.Opponents[i], settings.Depths[i] = s.opponent, s.depth
+	}
+	return players, settings
+}
+
+// loadSetup returns the setup of the last game, or defaultSetup if there isn't one that can be played
+func loadSetup() GameSetup {
+	data, err := os.ReadFile(setupFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultSetup
+	}
+	setup := defaultSetup
+	if err == nil {
+		err = json.Unmarshal(data, &setup)
+	}
+	if err == nil {
+		err = setup.check()
+	}
+	if err != nil {
+		fmt.Printf("The last game's setup in %s can't be used (%v), starting from the defaults.\n", setupFile, err)
+		return defaultSetup
+	}
+	return setup
+}
+
+// saveSetup remembers the setup for the next game
+func saveSetup(setup GameSetup) {
+	data, err := json.MarshalIndent(setup, "", "  ")
+	if err == nil {
+		err = os.WriteFile(setupFile, data, 0o644)
+	}
+	if err != nil {
+		fmt.Println("The setup could not be remembered:", err)
+	}
+}
+
+// The command line flags, registered by RegisterFlags
+var (
+	setupFlags *flag.FlagSet
+	flagSetup  GameSetup
+	flagFirst  string
+)
+
+// RegisterFlags adds command line flags for the pre-game setup to fs, call it before fs is parsed.
+// The setup choices given as flags aren't asked for.
+func RegisterFlags(fs *flag.FlagSet) {
+	setupFlags = fs
+	names := make([]string, 0, len(difficulties))
+	for _, d := range difficulties {
+		names = append(names, d.Name)
+	}
+	fs.StringVar(&flagSetup.Difficulty, "difficulty", "",
+		fmt.Sprintf("Connect 4 opponent: %s, %s to pick -engine and -depth, %s for two players or %s to watch two engines",
+			strings.Join(names, ", "), customDifficulty, humanDifficulty, computerDifficulty))
+	fs.IntVar(&flagSetup.Opponent, "engine", 0, "Connect 4 engine from the opponents menu, for -difficulty custom or the first of -difficulty computer")
+	fs.UintVar(&flagSetup.Depth, "depth", 0, "Connect 4 engine search depth, for -difficulty custom or the first of -difficulty computer")
+	fs.IntVar(&flagSetup.SecondOpponent, "second-engine", 0, "the second Connect 4 engine from the opponents menu, for -difficulty computer")
+	fs.UintVar(&flagSetup.SecondDepth, "second-depth", 0, "the second Connect 4 engine's search depth, for -difficulty computer")
+	fs.StringVar(&flagFirst, "first", "", "who moves first in Connect 4: human or computer (or the second human)")
+	fs.StringVar(&flagSetup.Names[0], "name", "", "your name in Connect 4, the first player's against another human")
+	fs.StringVar(&flagSetup.Names[1], "opponent-name", "", "the Connect 4 opponent's name")
+	fs.StringVar(&flagSetup.Glyphs, "glyphs", "", "the two characters Connect 4 pieces are drawn with, the first player's first")
+}
+
+// applyFlags sets the choices given on the command line in setup and returns which were given by flag name.
+// A choice that can't be played is reported and asked for instead.
+func applyFlags(setup *GameSetup) map[string]bool {
+	given := map[string]bool{}
+	if setupFlags == nil || !setupFlags.Parsed() {
+		return given
+	}
+	setupFlags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if given["difficulty"] {
+		setup.Difficulty = strings.ToLower(flagSetup.Difficulty)
+	}
+	if given["engine"] || given["depth"] {
+		// an engine makes it a custom game unless -difficulty computer asks for two computers
+		if !given["difficulty"] || setup.Difficulty != computerDifficulty {
+			setup.Difficulty = customDifficulty
+		}
+		given["difficulty"] = true
+		setup.Opponent, setup.Depth = flagSetup.Opponent, flagSetup.Depth
+	}
+	if given["second-engine"] || given["second-depth"] {
+		setup.SecondOpponent, setup.SecondDepth = flagSetup.SecondOpponent, flagSetup.SecondDepth
+	}
+	if given["first"] {
+		switch strings.ToLower(flagFirst) {
+		case "human", "you":
+			setup.HumanFirst = true
+		case "computer", "second":
+			setup.HumanFirst = false
+		default:
+			fmt.Printf("-first %q is neither human nor computer.\n", flagFirst)
+			given["first"] = false
+		}
+	}
+	if given["name"] {
+		setup.Names[0] = flagSetup.Names[0]
+	}
+	if given["opponent-name"] {
+		setup.Names[1] = flagSetup.Names[1]
+	}
+	if given["glyphs"] {
+		setup.Glyphs = flagSetup.Glyphs
+	}
+
+	if err := setup.check(); err != nil {
+		fmt.Printf("The command line setup can't be played (%v), please choose.\n", err)
+		return map[string]bool{}
+	}
+	return given
+}
+
+// promptSetup asks for every choice of the setup that wasn't given, offering the current ones as the defaults
+func promptSetup(setup GameSetup, given map[string]bool) GameSetup {
+	if !given["difficulty"] {
+		setup = promptDifficulty(setup)
+	}
+	hotseat := setup.Difficulty == humanDifficulty
+	watching := setup.Difficulty == computerDifficulty
+
+	if !given["first"] {
+		if hotseat || watching {
+			setup.HumanFirst = true // the first name moves first
+		} else {
+			setup.HumanFirst = promptYesNoDefault("Do you want to move first?", setup.HumanFirst)
+		}
+	}
+
+	if hotseat && setup.Names[1] == defaultSetup.Names[1] {
+		setup.Names[1] = "Player 2"
+	}
+	if watching && setup.Names == defaultSetup.Names {
+		setup.Names = [2]string{"Computer 1", "Computer 2"}
+	}
+	nameQuestions := [2]string{"Your name", "The computer's name"}
+	switch {
+	case hotseat:
+		nameQuestions = [2]string{"First player's name", "Second player's name"}
+	case watching:
+		nameQuestions = [2]string{"First computer's name", "Second computer's name"}
+	}
+	for i, flagName := range [2]string{"name", "opponent-name"} {
+		if !given[flagName] {
+			setup.Names[i] = promptDefault(nameQuestions[i], setup.Names[i])
+		}
+	}
+
+	for !given["glyphs"] {
+		answer := setup
+		answer.Glyphs = promptDefault("Glyphs for the first and second player's pieces", setup.Glyphs)
+		if err := answer.check(); err != nil {
+			fmt.Println("Please enter two different characters, like XO.")
+			continue
+		}
+		setup = answer
+		break
+	}
+	return setup
+}
+
+// promptDifficulty shows the difficulties, a custom engine, another human and two computers and reads a choice
+func promptDifficulty(setup GameSetup) GameSetup {
+	custom, human, computer := len(difficulties)+1, len(difficulties)+2, len(difficulties)+3
+	current := 0
+	for {
+		fmt.Println("Please select your opponent:")
+		for i, d := range difficulties {
+			fmt.Printf("  %2d) %s (%s)\n", i+1, strings.ToUpper(d.Name[:1])+d.Name[1:], d.Description)
+			if d.Name == setup.Difficulty {
+				current = i + 1
+			}
+		}
+		fmt.Printf("  %2d) Custom (pick the engine and its depth)\n", custom)
+		fmt.Printf("  %2d) Another human at this terminal\n", human)
+		fmt.Printf("  %2d) Watch two computers play each other (pick both engines)\n", computer)
+		switch setup.Difficulty {
+		case customDifficulty:
+			current = custom
+		case humanDifficulty:
+			current = human
+		case computerDifficulty:
+			current = computer
+		}
+
+		answer := promptDefault("Enter choice", fmt.Sprint(current))
+		var choice int
+		if _, err := fmt.Sscanf(answer, "%d", &choice); err != nil || choice < 1 || choice > computer {
+			fmt.Println("Selection not available. Choose one of the listed numbers.")
+			continue
+		}
+
+		switch choice {
+		case custom:
+			opponent, depth, ok := promptEngine()
+			if !ok {
+				return setup // nothing more to read, the difficulty stays as it was
+			}
+			setup.Difficulty, setup.Opponent, setup.Depth = customDifficulty, opponent, depth
+		case computer:
+			fmt.Println("The first computer:")
+			first, firstDepth, ok := promptEngine()
+			if !ok {
+				return setup
+			}
+			fmt.Println("The second computer:")
+			second, secondDepth, ok := promptEngine()
+			if !ok {
+				return setup
+			}
+			setup.Difficulty = computerDifficulty
+			setup.Opponent, setup.Depth = first, firstDepth
+			setup.SecondOpponent, setup.SecondDepth = second, secondDepth
+		case human:
+			setup.Difficulty = humanDifficulty
+		default:
+			setup.Difficulty = difficulties[choice-1].Name
+		}
+		return setup
+	}
+}
+
+// promptEngine asks for an engine from the opponents menu and, if it searches to a depth, the depth.
+// Returns false once there is nothing left to read.
+func promptEngine() (int, uint, bool) {
+	opponent, ok := promptOpponent()
+	if !ok {
+		return 0, 0, false
+	}
+	var depth uint
+	if choice := opponents[opponent]; choice.WithDepth != nil {
+		depth = promptDepth(choice.DefaultDepth)
+	}
+	return opponent, depth, true
+}
+
+// promptDefault asks question showing the current answer, which an empty answer keeps
+func promptDefault(question, current string) string {
+	fmt.Printf("%s [%s]: ", question, current)
+	if answer, _ := readLine(); answer != "" {
+		return answer
+	}
+	return current
+}
+
+// promptYesNoDefault is promptYesNo where an empty answer is current
+func promptYesNoDefault(question string, current bool) bool {
+	for {
+		answer := "n"
+		if current {
+			answer = "y"
+		}
+		switch strings.ToLower(promptDefault(question+" (y/n)", answer)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Println("Please answer y or n.")
+	}
+}
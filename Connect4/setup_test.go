@@ -0,0 +1,127 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetupPlayers(t *testing.T) {
+	setup := GameSetup{Difficulty: "hard", HumanFirst: false, Names: [2]string{"Ann", "Bot"}, Glyphs: "XO"}
+	if err := setup.check(); err != nil {
+		t.Fatal(err)
+	}
+	players, settings := setup.players()
+	if !players[1].IsHuman || players[1].Name != "Ann" || players[1].Piece != CpuIcon {
+		t.Errorf("second player %+v, want the human Ann", players[1])
+	}
+	if players[0].IsHuman || players[0].Name != "Bot" || players[0].Piece != PlayerIcon {
+		t.Errorf("first player %+v, want the computer Bot", players[0])
+	}
+	if settings.Opponents != [2]int{4, 0} || settings.Depths != [2]uint{cpuSearchDepth, 0} {
+		t.Errorf("settings %+v", settings)
+	}
+
+	setup = GameSetup{Difficulty: customDifficulty, Opponent: 3, Depth: 2, HumanFirst: true, Names: [2]string{"Ann", "Bot"}, Glyphs: "XO"}
+	players, _ = setup.players()
+	if e, ok := players[1].Engine.(*MiniMaxEngine); !ok || e.Depth != 2 {
+		t.Errorf("custom engine %#v, want minimax at depth 2", players[1].Engine)
+	}
+
+	setup.Difficulty = humanDifficulty
+	if players, _ = setup.players(); !players[0].IsHuman || !players[1].IsHuman {
+		t.Errorf("hotseat players %+v", players)
+	}
+
+	setup = GameSetup{Difficulty: computerDifficulty, Opponent: 3, Depth: 2, SecondOpponent: 2, HumanFirst: true,
+		Names: [2]string{"Max", "Greta"}, Glyphs: "XO"}
+	if err := setup.check(); err != nil {
+		t.Fatal(err)
+	}
+	players, settings = setup.players()
+	if players[0].IsHuman || players[0].Name != "Max" || players[1].IsHuman || players[1].Name != "Greta" {
+		t.Errorf("computer players %+v, want Max and then Greta", players)
+	}
+	if _, ok := players[1].Engine.(GreedyEngine); !ok {
+		t.Errorf("second computer %#v, want greedy", players[1].Engine)
+	}
+	if settings.Opponents != [2]int{3, 2} || settings.Depths != [2]uint{2, 0} {
+		t.Errorf("computer settings %+v", settings)
+	}
+}
+
+func TestSetupCheck(t *testing.T) {
+	for _, d := range difficulties {
+		setup := defaultSetup
+		setup.Difficulty = d.Name
+		if err := setup.check(); err != nil {
+			t.Errorf("difficulty %s: %v", d.Name, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		edit func(s *GameSetup)
+	}{
+		{"unknown difficulty", func(s *GameSetup) { s.Difficulty = "impossible" }},
+		{"unknown engine", func(s *GameSetup) { s.Difficulty, s.Opponent = customDifficulty, 99 }},
+		{"too deep", func(s *GameSetup) { s.Difficulty, s.Opponent, s.Depth = customDifficulty, 3, maxEngineDepth+1 }},
+		{"one computer", func(s *GameSetup) { s.Difficulty, s.Opponent = computerDifficulty, 3 }},
+		{"second too deep", func(s *GameSetup) {
+			s.Difficulty, s.Opponent, s.SecondOpponent, s.SecondDepth = computerDifficulty, 3, 3, maxEngineDepth+1
+		}},
+		{"no name", func(s *GameSetup) { s.Names[1] = "" }},
+		{"one glyph", func(s *GameSetup) { s.Glyphs = "X" }},
+		{"same glyphs", func(s *GameSetup) { s.Glyphs = "XX" }},
+		{"bar glyph", func(s *GameSetup) { s.Glyphs = "X|" }},
+	}
+	for _, tt := range tests {
+		setup := defaultSetup
+		tt.edit(&setup)
+		if setup.check() == nil {
+			t.Errorf("%s: check() succeeded", tt.name)
+		}
+	}
+}
+
+func TestNewGlyphs(t *testing.T) {
+	for _, bad := range [][2]string{{"X", "X"}, {"", "O"}, {"XY", "O"}, {" ", "O"}, {"X", "|"}, {"\t", "O"}} {
+		if _, err := NewGlyphs(bad[0], bad[1]); !errors.Is(err, ErrInvalidPiece) {
+			t.Errorf("NewGlyphs(%q, %q) error = %v, want ErrInvalidPiece", bad[0], bad[1], err)
+		}
+	}
+
+	glyphs, err := NewGlyphs("●", "○")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBoard().PlayMoves("12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Draw(glyphs); !strings.Contains(got, "●") || !strings.Contains(got, "○") || b.Grid() != "7/7/7/7/7/+*5 + 4" {
+		t.Errorf("board drawn as\n%s with grid %q", got, b.Grid())
+	}
+	if got := b.String(); strings.Contains(got, "●") || got != b.Draw(Glyphs{}) {
+		t.Errorf("String() drew the glyphs:\n%s", got)
+	}
+	bb, err := ToBitBoard(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bb.Draw(glyphs) != b.Draw(glyphs) || bb.String() != b.String() {
+		t.Errorf("bitboard drawn as\n%s, want\n%s", bb.Draw(glyphs), b.Draw(glyphs))
+	}
+}
+
+func TestPromptSetupComputers(t *testing.T) {
+	// two computers, minimax at depth 2 against greedy, with the names and glyphs offered
+	withStdin(t, fmt.Sprintf("%d\n3\n2\n2\n\n\n\n", len(difficulties)+3))
+	setup := promptSetup(defaultSetup, map[string]bool{})
+	want := GameSetup{Difficulty: computerDifficulty, Opponent: 3, Depth: 2, SecondOpponent: 2, HumanFirst: true,
+		Names: [2]string{"Computer 1", "Computer 2"}, Glyphs: DefaultGlyphs}
+	if setup != want {
+		t.Errorf("promptSetup() = %+v, want %+v", setup, want)
+	}
+}
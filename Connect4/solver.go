@@ -0,0 +1,429 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// ------------------------------------------------------
+// Perfect play solver
+// ------------------------------------------------------
+// The solver finds the game-theoretic value of a position instead of a heuristic score.
+// Scores follow the usual convention for Connect4 solvers:
+//   - 0 means the game is a draw with perfect play
+//   - a positive score means the player to move wins, the sooner the win the higher the score.
+//     Winning with your k-th from last stone scores k, so winning with your very last stone scores 1
+//   - a negative score means the player to move loses, by the same scale
+const (
+	boardCells = NumCols * NumRows
+	minScore   = -(boardCells)/2 + 3
+	maxScore   = (boardCells+1)/2 - 3
+)
+
+// Solution is the result of solving a position for the player to move
+type Solution struct {
+	Move  Move   // the best move, when several moves are equally good the most central one
+	Score int    // the exact score for the player to move, see the scoring convention above
+	Plies int    // how many more moves the game lasts with perfect play, counting both players
+	Nodes uint64 // positions searched to find the solution
+}
+
+// IsWin reports whether the player to move wins with perfect play
+func (s Solution) IsWin() bool { return s.Score > 0 }
+
+// IsLoss reports whether the player to move loses against perfect play
+func (s Solution) IsLoss() bool { return s.Score < 0 }
+
+// IsDraw reports whether the position is a draw with perfect play
+func (s Solution) IsDraw() bool { return s.Score == 0 }
+
+// Solver solves positions with a negamax search using null windows, move ordering and a transposition table.
+// The table is kept between calls so solving several positions from the same game gets faster.
+// A Solver is not safe for concurrent use.
+type Solver struct {
+	table   solverTable
+	nodes   uint64
+	ctx     context.Context // cancels a solve in progress, nil never does
+	stopped bool            // set once ctx is done, see stop
+}
+
+// Number of entries in the solver's transposition table, a prime keeps the keys spread evenly
+const solverTableSize = (1 << 22) + 15
+
+// NewSolver returns a Solver with an empty transposition table
+func NewSolver() *Solver {
+	return &Solver{table: newSolverTable(solverTableSize)}
+}
+
+// Solve returns the exact value of the board for p, who is the player to move, and p's best move.
+// PlayerIcon moves first so the stones on the board tell whose move it is,
+// ErrNotToMove is returned if it isn't p's.
+// Solving positions in the first few moves of the game can take minutes.
+// Only four in a row on the standard NumCols x NumRows board can be solved,
+// other sizes and win lengths return ErrUnsupportedSize and PopOut boards ErrUnsupportedVariant.
+func (s *Solver) Solve(b C4Board, p Player) (Solution, error) {
+	return s.SolveContext(context.Background(), b, p)
+}
+
+// SolveContext is Solve that gives up when ctx is done, returning ctx.Err().
+// What was learned before that stays in the table for the next solve.
+func (s *Solver) SolveContext(ctx context.Context, b C4Board, p Player) (Solution, error) {
+	if b.popOut {
+		return Solution{}, fmt.Errorf("%w: the solver doesn't play PopOut", ErrUnsupportedVariant)
+	}
+	if b.numCols != NumCols || b.numRows != NumRows || b.winLength != WinLength {
+		return Solution{}, fmt.Errorf("%w: the solver only plays %d in a row on %dx%d boards",
+			ErrUnsupportedSize, WinLength, NumCols, NumRows)
+	}
+	if b.IsGameOver() {
+		return Solution{}, ErrGameOver
+	}
+	stones := b.numCols*b.numRows - b.emptyCells()
+	toMove := PlayerIcon
+	if stones%2 == 1 {
+		toMove = CpuIcon
+	}
+	if p.Piece != toMove {
+		return Solution{}, fmt.Errorf("%w: %q is not to move with %d stones on the board", ErrNotToMove, p.Piece.String(), stones)
+	}
+
+	s.nodes, s.ctx, s.stopped = 0, ctx, false
+	pos := newSolverPosition(b, p.Piece)
+	solution := Solution{Score: s.solve(pos)}
+
+	// The best move is the first one (center first) whose reply is worth at most -Score to the opponent
+	for _, col := range solverColumnOrder {
+		if !pos.canPlay(col) {
+			continue
+		}
+		if pos.isWinningMove(col) {
+			solution.Move = Move(col)
+			break
+		}
+
+		child := pos
+		child.play(pos.possible() & columnMask(col))
+		var reply int
+		if child.canWinNext() {
+			reply = (boardCells + 1 - child.moves) / 2
+		} else {
+			reply = s.negamax(child, -solution.Score, -solution.Score+1)
+		}
+		if reply <= -solution.Score {
+			solution.Move = Move(col)
+			break
+		}
+	}
+	if s.stopped {
+		return Solution{}, ctx.Err()
+	}
+
+	solution.Plies = pliesToEnd(solution.Score, pos.moves)
+	solution.Nodes = s.nodes
+	return solution, nil
+}
+
+// Solve solves a single position with a fresh Solver
+func Solve(b C4Board, p Player) (Solution, error) {
+	return NewSolver().Solve(b, p)
+}
+
+// pliesToEnd turns a score into the number of moves left in the game, moves being the stones already played
+func pliesToEnd(score int, moves int) int {
+	if score == 0 {
+		return boardCells - moves
+	}
+
+	// the winner's last stone is stone number last, whose parity tells who played it
+	winnerParity := moves % 2 // the player to move plays the stones with the same parity as moves
+	if score < 0 {
+		score = -score
+		winnerParity = 1 - winnerParity
+	}
+	last := boardCells + 1 - 2*score
+	if last%2 != winnerParity {
+		last--
+	}
+	return last - moves + 1
+}
+
+// solve finds the exact score of a position the player to move can't win immediately,
+// narrowing down the score with null window searches
+func (s *Solver) solve(pos solverPosition) int {
+	if pos.canWinNext() {
+		return (boardCells + 1 - pos.moves) / 2
+	}
+
+	lower := -(boardCells - pos.moves) / 2
+	upper := (boardCells + 1 - pos.moves) / 2
+	for lower < upper {
+		med := lower + (upper-lower)/2
+		// probe closer to 0 first, most positions are decided by a small margin
+		if med <= 0 && lower/2 < med {
+			med = lower / 2
+		} else if med >= 0 && upper/2 > med {
+			med = upper / 2
+		}
+
+		r := s.negamax(pos, med, med+1)
+		if s.stopped {
+			return 0
+		}
+		if r <= med {
+			upper = r
+		} else {
+			lower = r
+		}
+	}
+	return lower
+}
+
+// stop reports whether the solve has to give up, ctx is only checked every cancelCheckInterval nodes
+func (s *Solver) stop() bool {
+	if !s.stopped && s.ctx != nil && s.nodes%cancelCheckInterval == 1 && s.ctx.Err() != nil {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// negamax returns the score of pos if it lies in (alpha, beta), otherwise a bound on the
+// score on the same side of the window. The player to move must not have a winning move.
+func (s *Solver) negamax(pos solverPosition, alpha, beta int) int {
+	s.nodes++
+	if s.stop() {
+		return 0
+	}
+
+	next := pos.possibleNonLosingMoves()
+	if next == 0 {
+		// every move lets the opponent win straight away
+		return -(boardCells - pos.moves) / 2
+	}
+	if pos.moves >= boardCells-2 {
+		// neither player can win with the last two stones
+		return 0
+	}
+
+	// The opponent can't win with their next stone so we lose at the earliest after that
+	lower := -(boardCells - 2 - pos.moves) / 2
+	if alpha < lower {
+		alpha = lower
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
+	// We can't win with our next stone so we win at the earliest with the one after
+	upper := (boardCells - 1 - pos.moves) / 2
+	if value := s.table.get(pos.key()); value != 0 {
+		if value > maxScore-minScore+1 {
+			lower = value + 2*minScore - maxScore - 2
+			if alpha < lower {
+				alpha = lower
+				if alpha >= beta {
+					return alpha
+				}
+			}
+		} else {
+			upper = value + minScore - 1
+		}
+	}
+	if beta > upper {
+		beta = upper
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	for _, move := range pos.orderMoves(next) {
+		child := pos
+		child.play(move)
+		score := -s.negamax(child, -beta, -alpha)
+		if s.stopped {
+			// the score of a search cut short must not go in the table
+			return 0
+		}
+
+		if score >= beta {
+			s.table.put(pos.key(), score+maxScore-2*minScore+2) // lower bound
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	s.table.put(pos.key(), alpha-minScore+1) // upper bound
+	return alpha
+}
+
+// ------------------------------------------------------
+// Solver position
+// ------------------------------------------------------
+// solverPosition is a bitboard seen from the side of the player to move,
+// which makes negamax simpler than BitBoard's one mask per piece.
+type solverPosition struct {
+	current uint64 // stones of the player to move
+	mask    uint64 // every stone on the board
+	moves   int    // stones played so far
+}
+
+// The solver only plays on the standard board, which it lays out the same way as a standard BitBoard
+const solverColHeight = NumRows + 1
+
+var solverLayout = bitLayouts[[3]uint{NumCols, NumRows, WinLength}]
+
+// bottomMask has the bottom cell of every column set and boardMask every playable cell
+var bottomMask, boardMask = solverLayout.bottom, solverLayout.full
+
+// Columns in the order they are searched, center first
+var solverColumnOrder = centerOrder(NumCols)
+
+// centerOrder returns the columns of a board numCols wide from the center outwards
+func centerOrder(numCols int) []int {
+	order := make([]int, numCols)
+	for i := range order {
+		order[i] = numCols/2 + (1-2*(i%2))*(i+1)/2
+	}
+	return order
+}
+
+// newSolverPosition builds the position of the board with toMove to play
+func newSolverPosition(b C4Board, toMove Piece) solverPosition {
+	bb, _ := ToBitBoard(b)
+	pos := solverPosition{
+		current: bb.masks[toMove],
+		mask:    bb.masks[PlayerIcon] | bb.masks[CpuIcon],
+	}
+	pos.moves = bits.OnesCount64(pos.mask)
+	return pos
+}
+
+// columnMask has every cell of column col set
+func columnMask(col int) uint64 {
+	return ((1 << NumRows) - 1) << (col * solverColHeight)
+}
+
+// key uniquely identifies the position, the extra top bit of each column marks its height
+func (pos solverPosition) key() uint64 {
+	return pos.current + pos.mask
+}
+
+// canPlay reports whether the top cell of column col is still empty
+func (pos solverPosition) canPlay(col int) bool {
+	return pos.mask&(1<<(NumRows-1+col*solverColHeight)) == 0
+}
+
+// play drops a stone in the cell of move (a single bit) and passes the turn
+func (pos *solverPosition) play(move uint64) {
+	pos.current ^= pos.mask
+	pos.mask |= move
+	pos.moves++
+}
+
+// possible has a bit set for the cell a stone would land in for each non full column
+func (pos solverPosition) possible() uint64 {
+	return (pos.mask + bottomMask) & boardMask
+}
+
+func (pos solverPosition) winningPosition() uint64 {
+	return winningCells(pos.current, pos.mask)
+}
+
+func (pos solverPosition) opponentWinningPosition() uint64 {
+	return winningCells(pos.current^pos.mask, pos.mask)
+}
+
+func (pos solverPosition) canWinNext() bool {
+	return pos.winningPosition()&pos.possible() != 0
+}
+
+func (pos solverPosition) isWinningMove(col int) bool {
+	return pos.winningPosition()&pos.possible()&columnMask(col) != 0
+}
+
+// possibleNonLosingMoves returns the moves that don't hand the opponent an immediate win.
+// Only valid if the player to move can't win straight away.
+func (pos solverPosition) possibleNonLosingMoves() uint64 {
+	possible := pos.possible()
+	opponentWin := pos.opponentWinningPosition()
+	forced := possible & opponentWin
+	if forced != 0 {
+		if forced&(forced-1) != 0 {
+			return 0 // the opponent has two threats, we can only block one
+		}
+		possible = forced
+	}
+	// don't play directly below a cell the opponent wins with
+	return possible &^ (opponentWin >> 1)
+}
+
+// orderMoves returns the moves in next ordered by how many winning cells they
+// create for the player to move, ties go to the more central column
+func (pos solverPosition) orderMoves(next uint64) []uint64 {
+	type scoredMove struct {
+		move  uint64
+		score int
+	}
+	moves := make([]scoredMove, 0, NumCols)
+	for _, col := range solverColumnOrder {
+		if move := next & columnMask(col); move != 0 {
+			threats := bits.OnesCount64(winningCells(pos.current|move, pos.mask))
+			moves = append(moves, scoredMove{move: move, score: threats})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].score > moves[j].score })
+
+	ordered := make([]uint64, len(moves))
+	for i, m := range moves {
+		ordered[i] = m.move
+	}
+	return ordered
+}
+
+// winningCells returns the empty cells that would complete four in a row for the stones in position
+func winningCells(position, mask uint64) uint64 {
+	// vertical
+	r := (position << 1) & (position << 2) & (position << 3)
+
+	// horizontal and both diagonals, the missing cell can be anywhere in the line of four
+	for _, shift := range []uint{solverColHeight, solverColHeight - 1, solverColHeight + 1} {
+		p := (position << shift) & (position << (2 * shift))
+		r |= p & (position << (3 * shift))
+		r |= p & (position >> shift)
+		p = (position >> shift) & (position >> (2 * shift))
+		r |= p & (position << shift)
+		r |= p & (position >> (3 * shift))
+	}
+
+	return r & (boardMask ^ mask)
+}
+
+// ------------------------------------------------------
+// Solver transposition table
+// ------------------------------------------------------
+// solverTable stores one small bound per position, 0 means nothing is stored
+type solverTable struct {
+	keys   []uint64
+	values []int8
+}
+
+func newSolverTable(size int) solverTable {
+	return solverTable{keys: make([]uint64, size), values: make([]int8, size)}
+}
+
+func (t solverTable) put(key uint64, value int) {
+	i := key % uint64(len(t.keys))
+	t.keys[i] = key
+	t.values[i] = int8(value)
+}
+
+func (t solverTable) get(key uint64) int {
+	i := key % uint64(len(t.keys))
+	if t.keys[i] == key {
+		return int(t.values[i])
+	}
+	return 0
+}
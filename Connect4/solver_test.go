@@ -0,0 +1,101 @@
+package connect4
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Positions with their exact scores for the player to move, checked with a full brute force search
+var solverPositions = []struct {
+	moves string
+	score int
+	plies int
+}{
+	{"2364171236641561122122677643745", 6, 1},
+	{"7656173266347545761635534732154", -5, 2},
+	{"16713713477771114565622562534", -6, 2},
+	{"6527725725667132233215", 8, 5},
+	{"2363352763336211561615", -3, 16},
+	{"6717436345333656755765316", 0, 17},
+	{"77156524323777761213411", 9, 3},
+	{"444164251553117145235165", 8, 3},
+	{"26344264274773446372175156", 1, 15},
+	{"216135471736773123447645", 0, 18},
+	{"726212517655235134521217", 7, 5},
+	{"7515513554472476514246741", 2, 15},
+	{"642731441777252344133267", 2, 15},
+	{"3331732766144777444723", 9, 3},
+	{"7667135354542615656633227", 4, 11},
+	{"2634426427477344", 1, 25},
+	{"26344264274773", 0, 28},
+}
+
+func TestSolve(t *testing.T) {
+	solver := NewSolver()
+	for _, tc := range solverPositions {
+		b, p := playMoves(t, tc.moves)
+
+		solution, err := solver.Solve(b, p)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.moves, err)
+		}
+		if solution.Score != tc.score || solution.Plies != tc.plies {
+			t.Errorf("%s: got score %d in %d plies, want %d in %d plies",
+				tc.moves, solution.Score, solution.Plies, tc.score, tc.plies)
+		}
+
+		// The best move has to keep the score: either it wins on the spot or
+		// the opponent is left with exactly the negated score
+		after := b.MakeMove(p, solution.Move)
+		if after.IsWin() {
+			continue
+		}
+		reply, err := solver.Solve(after, p.Opponent())
+		if err != nil {
+			t.Fatalf("%s then %d: %v", tc.moves, solution.Move+1, err)
+		}
+		if reply.Score != -tc.score {
+			t.Errorf("%s: best move %d leaves the opponent a score of %d, want %d",
+				tc.moves, solution.Move+1, reply.Score, -tc.score)
+		}
+	}
+}
+
+func TestSolveGameOver(t *testing.T) {
+	b, p := playMoves(t, "1212121")
+	if _, err := Solve(b, p); !errors.Is(err, ErrGameOver) {
+		t.Errorf("got %v, want ErrGameOver", err)
+	}
+}
+
+func TestSolveWrongSideToMove(t *testing.T) {
+	for _, moves := range []string{"", "4", "2634426427477344"} {
+		b, p := playMoves(t, moves)
+		if _, err := Solve(b, p.Opponent()); !errors.Is(err, ErrNotToMove) {
+			t.Errorf("%q solved for %v: error = %v, want ErrNotToMove", moves, p.Opponent().Piece, err)
+		}
+	}
+}
+
+func TestSolveCancelled(t *testing.T) {
+	b, p := playMoves(t, "2363352763336211561615")
+	solver := NewSolver()
+
+	// cancelled after a few hundred nodes, long before the solve is done,
+	// with searches cut short part way through that must not be stored
+	ctx := &cancelledAfter{Context: context.Background(), n: 3}
+	if _, err := solver.SolveContext(ctx, b, p); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SolveContext error = %v, want context.Canceled", err)
+	}
+
+	// nothing the cancelled solve stored may change the answer
+	solution, err := solver.Solve(b, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Solve(b, p)
+	if solution.Score != want.Score || solution.Move != want.Move {
+		t.Errorf("after a cancelled solve: score %d move %v, want %d and %v", solution.Score, solution.Move, want.Score, want.Move)
+	}
+}
@@ -0,0 +1,325 @@
+package connect4
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------
+// Spectator mode - watching two engines play
+// ------------------------------------------------------
+// A Spectator plays the moves of a session between two computer players at a pace a person can
+// follow, taking commands to pause, step through the game a move at a time or stop it.
+// It is how changes to the evaluation are sanity checked: pit the new engine against the old,
+// watch where they disagree and read the summary at the end.
+
+// How long the spectator waits between moves unless told otherwise
+const defaultSpectatorDelay = time.Second
+
+// The deepest search that can be asked of an engine, deeper fixed-depth searches take far too long
+const maxEngineDepth uint = 20
+
+// SpectatorCommand controls a running Spectator
+type SpectatorCommand int
+
+const (
+	CommandPause SpectatorCommand = iota // pause, or carry on if paused
+	CommandStep                          // play one move while paused
+	CommandStop                          // end the match where it stands
+)
+
+// Spectator plays out a session between two computer players
+type Spectator struct {
+	Session  *Session
+	Delay    time.Duration           // wait between moves when not paused
+	Commands <-chan SpectatorCommand // nil for a match that can't be paused or stopped
+
+	// OnMove is called after each move with the search result of the engine that played it,
+	// scored is false if the engine couldn't say how good the move was
+	OnMove func(p Player, result SearchResult, scored bool)
+
+	// OnPause is called when the match is paused or carries on
+	OnPause func(paused bool)
+}
+
+// SideStats is how one side of a match played
+type SideStats struct {
+	Player   Player
+	Moves    int
+	Thinking time.Duration // time spent choosing moves
+	Nodes    uint64        // positions searched, for engines that report their search
+	Depths   uint          // sum of the depths reached, for engines that report their search
+	Searched int           // moves the engine reported its search for
+}
+
+// AverageThinking is how long the side took over a move
+func (s SideStats) AverageThinking() time.Duration {
+	if s.Moves == 0 {
+		return 0
+	}
+	return s.Thinking / time.Duration(s.Moves)
+}
+
+// AverageDepth is how deep the side's searches went, 0 if it didn't report any
+func (s SideStats) AverageDepth() float64 {
+	if s.Searched == 0 {
+		return 0
+	}
+	return float64(s.Depths) / float64(s.Searched)
+}
+
+// MatchSummary is how a match between two engines went
+type MatchSummary struct {
+	Result  GameResult
+	Moves   int
+	Stopped bool // stopped before the game ended
+	Sides   [2]SideStats
+}
+
+// String describes the match over a few lines, for printing when it is over
+func (m MatchSummary) String() string {
+	var sb strings.Builder
+	switch {
+	case m.Stopped:
+		fmt.Fprintf(&sb, "Stopped after %d moves\n", m.Moves)
+	case m.Result.Outcome == Draw:
+		fmt.Fprintf(&sb, "Draw after %d moves\n", m.Moves)
+	default:
+		for _, side := range m.Sides {
+			if side.Player.Piece == m.Result.Winner {
+				fmt.Fprintf(&sb, "%s (%v) won after %d moves\n", side.Player.Name, side.Player.Piece, m.Moves)
+			}
+		}
+	}
+
+	for _, side := range m.Sides {
+		fmt.Fprintf(&sb, "  %s (%v) %s: %d moves, %v per move",
+			side.Player.Name, side.Player.Piece, side.Player.Engine.Name(), side.Moves, side.AverageThinking().Round(time.Millisecond))
+		if side.Searched > 0 {
+			fmt.Fprintf(&sb, ", depth %.1f, %d nodes", side.AverageDepth(), side.Nodes)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Run plays the session until the game is over or it is stopped and returns how it went.
+// Every player must have an Engine.
+func (sp *Spectator) Run() MatchSummary {
+	s := sp.Session
+	var summary MatchSummary
+	for i, p := range s.Players() {
+		summary.Sides[i].Player = p
+	}
+
+	commands := sp.Commands
+	paused := false
+	for !s.IsOver() {
+		// wait for the next move to be due, or for a step while paused
+		var command SpectatorCommand
+		var ok bool
+		if paused {
+			command, ok = <-commands
+		} else {
+			timer := time.NewTimer(sp.Delay)
+			select {
+			case command, ok = <-commands:
+				timer.Stop()
+			case <-timer.C:
+				command, ok = CommandStep, true
+			}
+		}
+		if !ok {
+			// nobody left to give commands, play the rest of the game
+			commands, paused = nil, false
+			continue
+		}
+
+		switch command {
+		case CommandPause:
+			paused = !paused
+			if sp.OnPause != nil {
+				sp.OnPause(paused)
+			}
+			continue
+		case CommandStop:
+			summary.Stopped = true
+			summary.Result = s.Result()
+			summary.Moves = len(s.History())
+			return summary
+		}
+
+		side := &summary.Sides[s.next]
+		p := s.ToMove()
+		start := time.Now()
+		result, scored := engineMove(s.Board(), p)
+		side.Thinking += time.Since(start)
+		if _, searched := p.Engine.(SearchEngine); searched {
+			side.Nodes += result.Nodes
+			side.Depths += result.Depth
+			side.Searched++
+		}
+
+		if err := s.Play(result.Move); err != nil {
+			// an engine that can't find a legal move has nothing left to play
+			summary.Stopped = true
+			break
+		}
+		side.Moves++
+		if sp.OnMove != nil {
+			sp.OnMove(p, result, scored)
+		}
+	}
+
+	summary.Result = s.Result()
+	summary.Moves = len(s.History())
+	return summary
+}
+
+// WatchConnect4 asks for two engines and the board and lets the user watch them play each other.
+// Pressing Enter pauses and carries on, s and Enter plays one move while paused and q and Enter stops the game.
+func WatchConnect4() {
+	fmt.Println("------------- Connect 4 engine match -------------")
+
+	var players [2]Player
+	for seat := range players {
+		fmt.Printf("Engine for %s (moves %s):\n", seatPieces[seat], [2]string{"first", "second"}[seat])
+		choice, ok := promptOpponent()
+		if !ok {
+			return
+		}
+		opponent := opponents[choice]
+		players[seat] = Player{Name: fmt.Sprintf("Computer %d", seat+1), TurnCount: incrementer(), Piece: seatPieces[seat]}
+		players[seat].Engine = opponent.NewEngine()
+		if opponent.WithDepth != nil {
+			players[seat].Engine = opponent.WithDepth(promptDepth(opponent.DefaultDepth))
+		}
+	}
+	delay := promptDelay()
+
+	session := NewSessionOn(promptBoard(), players[0], players[1])
+	recorder := NewGameRecorder(session)
+	session.AddListener(ConsoleListener{})
+	session.AddListener(recorder)
+
+	// commands are read while the engines play, until the game is over
+	done := make(chan struct{})
+	commands := readSpectatorCommands(done)
+
+	fmt.Printf("\n%s (%v) %s against %s (%v) %s\n", players[0].Name, players[0].Piece, players[0].Engine.Name(),
+		players[1].Name, players[1].Piece, players[1].Engine.Name())
+	fmt.Println("Press Enter to pause or carry on, s and Enter to play one move while paused, q and Enter to stop.")
+	fmt.Println("\nCurrent Board:")
+	fmt.Printf("%s", session.Board().String())
+
+	spectator := Spectator{
+		Session:  session,
+		Delay:    delay,
+		Commands: commands,
+		OnMove: func(p Player, result SearchResult, scored bool) {
+			if _, searched := p.Engine.(SearchEngine); searched {
+				fmt.Printf("%s analysis: %s\n", p.Name, result)
+			}
+			if scored {
+				recorder.Annotate(result)
+			}
+		},
+		OnPause: func(paused bool) {
+			if paused {
+				fmt.Println("Paused, press Enter to carry on or s and Enter to play one move.")
+			} else {
+				fmt.Println("Carrying on.")
+			}
+		},
+	}
+	summary := spectator.Run()
+	close(done)
+
+	fmt.Println("\n------------- Match summary -------------")
+	fmt.Print(summary)
+	if err := AppendRecord(recordFile, recorder.Record()); err != nil {
+		fmt.Println("The game could not be recorded:", err)
+	} else {
+		fmt.Printf("The game was added to %s\n", recordFile)
+	}
+}
+
+// readSpectatorCommands sends the commands typed in until the end of the input, a stop command
+// or done is closed, then closes the channel. The lines after that are left for the next prompt.
+func readSpectatorCommands(done <-chan struct{}) <-chan SpectatorCommand {
+	commands := make(chan SpectatorCommand)
+	go func() {
+		defer close(commands)
+		lines := inputLines()
+		for {
+			var line string
+			var ok bool
+			select {
+			case line, ok = <-lines:
+			case <-done:
+				return
+			}
+			if !ok {
+				return
+			}
+
+			var command SpectatorCommand
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "":
+				command = CommandPause
+			case "s", "step":
+				command = CommandStep
+			case "q", "quit", "stop":
+				command = CommandStop
+			default:
+				continue
+			}
+			select {
+			case commands <- command:
+			case <-done:
+				return
+			}
+			if command == CommandStop {
+				return
+			}
+		}
+	}()
+	return commands
+}
+
+// promptDepth asks how many plies an engine should search, empty answers and the end of the input are defaultDepth
+func promptDepth(defaultDepth uint) uint {
+	for {
+		fmt.Printf("Search depth in plies, from 1 to %d (Enter for %d): ", maxEngineDepth, defaultDepth)
+
+		answer, _ := readLine()
+		if answer == "" {
+			return defaultDepth
+		}
+		var depth uint
+		if _, err := fmt.Sscanf(answer, "%d", &depth); err != nil || depth < 1 || depth > maxEngineDepth {
+			fmt.Printf("Please enter a number from 1 to %d.\n", maxEngineDepth)
+			continue
+		}
+		return depth
+	}
+}
+
+// promptDelay asks how long to wait between moves, empty answers and the end of the input are defaultSpectatorDelay
+func promptDelay() time.Duration {
+	for {
+		fmt.Printf("Seconds between moves (Enter for %v): ", defaultSpectatorDelay.Seconds())
+
+		answer, _ := readLine()
+		if answer == "" {
+			return defaultSpectatorDelay
+		}
+		var seconds float64
+		if _, err := fmt.Sscanf(answer, "%g", &seconds); err != nil || seconds < 0 {
+			fmt.Println("Please enter a number of seconds, like 0.5.")
+			continue
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+}
@@ -0,0 +1,111 @@
+package connect4
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestMatch() *Session {
+	return NewSession(
+		Player{Name: "Greedy", Piece: PlayerIcon, Engine: GreedyEngine{}},
+		Player{Name: "Minimax", Piece: CpuIcon, Engine: NewMiniMaxEngine(2)})
+}
+
+func TestSpectatorPlaysToTheEnd(t *testing.T) {
+	s := newTestMatch()
+	var moves int
+	sp := Spectator{Session: s, OnMove: func(p Player, result SearchResult, scored bool) {
+		moves++
+		if !scored {
+			t.Errorf("%s's move %v wasn't scored", p.Name, result.Move)
+		}
+	}}
+	summary := sp.Run()
+
+	if !s.IsOver() || summary.Stopped || summary.Result.Outcome == InProgress {
+		t.Fatalf("match not played to the end: %+v\n%s", summary, s.Board())
+	}
+	if summary.Moves != len(s.History()) || moves != summary.Moves ||
+		summary.Sides[0].Moves+summary.Sides[1].Moves != summary.Moves {
+		t.Errorf("%d moves played, summary %+v", moves, summary)
+	}
+	if summary.Sides[0].Searched != 0 || summary.Sides[1].Searched != summary.Sides[1].Moves ||
+		summary.Sides[1].AverageDepth() != 2 || summary.Sides[1].Nodes == 0 {
+		t.Errorf("search stats: %+v", summary.Sides)
+	}
+}
+
+func TestSpectatorPauseStepStop(t *testing.T) {
+	s := newTestMatch()
+	commands := make(chan SpectatorCommand)
+	var pauses []bool
+	sp := Spectator{Session: s, Delay: time.Hour, Commands: commands, OnPause: func(paused bool) { pauses = append(pauses, paused) }}
+
+	done := make(chan MatchSummary)
+	go func() { done <- sp.Run() }()
+
+	// with an hour between moves nothing is played until it is stepped
+	commands <- CommandPause
+	for i := 0; i < 3; i++ {
+		commands <- CommandStep
+	}
+	commands <- CommandPause
+	commands <- CommandPause
+	commands <- CommandStop
+	summary := <-done
+
+	if !summary.Stopped || summary.Moves != 3 || len(s.History()) != 3 {
+		t.Errorf("summary %+v after 3 steps", summary)
+	}
+	if len(pauses) != 3 || !pauses[0] || pauses[1] || !pauses[2] {
+		t.Errorf("pauses = %v, want [true false true]", pauses)
+	}
+}
+
+func TestSpectatorFinishesWhenCommandsEnd(t *testing.T) {
+	s := newTestMatch()
+	commands := make(chan SpectatorCommand)
+	close(commands)
+	sp := Spectator{Session: s, Commands: commands}
+	if summary := sp.Run(); summary.Stopped || !s.IsOver() {
+		t.Errorf("summary %+v, want the game played out", summary)
+	}
+}
+
+func TestReadSpectatorCommands(t *testing.T) {
+	withStdin(t, "\nhello\ns\nq\n\n")
+	var got []SpectatorCommand
+	for command := range readSpectatorCommands(make(chan struct{})) {
+		got = append(got, command)
+	}
+	if want := []SpectatorCommand{CommandPause, CommandStep, CommandStop}; !slices.Equal(got, want) {
+		t.Errorf("commands %v, want %v", got, want)
+	}
+	if line, ok := readLine(); line != "" || !ok {
+		t.Errorf("the line after q was %q, %v, want it left for the next prompt", line, ok)
+	}
+}
+
+func TestReadSpectatorCommandsDone(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	withStdinReader(t, r)
+
+	done := make(chan struct{})
+	commands := readSpectatorCommands(done)
+	io.WriteString(w, "s\n")
+	if command := <-commands; command != CommandStep {
+		t.Fatalf("got %v, want CommandStep", command)
+	}
+
+	// once the game is over the reader stops and the next answer goes to the prompt asking for it
+	close(done)
+	for range commands {
+	}
+	go io.WriteString(w, "answer\n")
+	if line, ok := readLine(); line != "answer" || !ok {
+		t.Errorf("readLine() = %q, %v after the commands stopped, want answer", line, ok)
+	}
+}
@@ -0,0 +1,274 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+)
+
+// ------------------------------------------------------
+// SPRT - does an engine change gain Elo?
+// ------------------------------------------------------
+// A sequential probability ratio test plays a candidate engine against a baseline, a game pair at
+// a time from the same random opening, until the results tell the two hypotheses apart:
+//
+//	H0: the candidate is at most Elo0 stronger than the baseline (the change doesn't help)
+//	H1: the candidate is at least Elo1 stronger (the change helps)
+//
+// After each game the log-likelihood ratio of H1 against H0 is worked out from the wins, draws
+// and losses. Once it falls below log(Beta / (1 - Alpha)) H0 is accepted and the change fails,
+// once it rises above log((1 - Beta) / Alpha) H1 is accepted and the change passes, so a change
+// that doesn't help passes with a chance of at most Alpha and one that does fails with a chance
+// of at most Beta. Clear cases are decided in a few games, close ones take thousands.
+//
+// Games are played at the same time but their results are counted in the order they were
+// scheduled, so a test with the same seed and engines that always play the same moves, such as
+// the fixed-depth MiniMaxEngine, plays the same games and comes to the same verdict every time.
+
+// The chances of a wrong verdict when SPRT.Alpha and SPRT.Beta aren't set
+const defaultSPRTError = 0.05
+
+// SPRTVerdict is what an SPRT concluded
+type SPRTVerdict int
+
+const (
+	SPRTUndecided SPRTVerdict = iota // ran out of games or was stopped before telling the hypotheses apart
+	SPRTPassed                       // H1 accepted, the candidate gains at least Elo1
+	SPRTFailed                       // H0 accepted, the candidate gains at most Elo0
+)
+
+func (v SPRTVerdict) String() string {
+	switch v {
+	case SPRTUndecided:
+		return "UNDECIDED"
+	case SPRTPassed:
+		return "PASS"
+	case SPRTFailed:
+		return "FAIL"
+	}
+	return fmt.Sprintf("SPRTVerdict(%d)", int(v))
+}
+
+// SPRT plays a candidate engine against a baseline until it can tell whether the candidate gains Elo
+type SPRT struct {
+	Candidate, Baseline Entrant
+	Elo0, Elo1          float64 // the Elo gains of H0 and H1, Elo1 must be more than Elo0
+	Alpha, Beta         float64 // the chances of passing a change that doesn't help and failing one that does, 0 for 5%
+	MaxGames            int     // games after which the test gives up undecided, 0 for no limit
+	Openings            uint    // random moves played before the engines take over, 0 to start from Board
+	Seed                int64   // picks the openings
+	Workers             int     // games played at once, 0 for one per CPU
+	Board               C4Board // the board every game starts on, the zero board for NewBoard
+
+	// OnGame is called as each game is counted, in the order they were scheduled
+	OnGame func(g TournamentGame, status SPRTStatus)
+}
+
+// SPRTStatus is how far an SPRT has got
+type SPRTStatus struct {
+	Score        Score   // the candidate's score against the baseline
+	LLR          float64 // the log-likelihood ratio of H1 against H0
+	Lower, Upper float64 // the LLR below which the change fails and above which it passes
+}
+
+// Verdict is what the status shows so far
+func (s SPRTStatus) Verdict() SPRTVerdict {
+	switch {
+	case s.LLR >= s.Upper:
+		return SPRTPassed
+	case s.LLR <= s.Lower:
+		return SPRTFailed
+	}
+	return SPRTUndecided
+}
+
+// String shows the score and the LLR with its bounds, like "120 games 40-50-30, LLR 1.23 [-2.94, 2.94]"
+func (s SPRTStatus) String() string {
+	return fmt.Sprintf("%d games %v, LLR %.2f [%.2f, %.2f]", s.Score.Games(), s.Score, s.LLR, s.Lower, s.Upper)
+}
+
+// SPRTResult is how an SPRT ended
+type SPRTResult struct {
+	SPRTStatus
+	Elo0, Elo1 float64
+	Games      []TournamentGame // the games counted, in the order they were scheduled
+}
+
+// Passed reports whether the candidate was shown to gain Elo, an undecided test doesn't pass
+func (r SPRTResult) Passed() bool {
+	return r.Verdict() == SPRTPassed
+}
+
+// String writes the verdict on the first line, starting with PASS, FAIL or UNDECIDED,
+// and the score, the Elo gain it shows and the LLR on the second
+func (r SPRTResult) String() string {
+	var sb strings.Builder
+	h0, h1 := formatEloValue(r.Elo0, true), formatEloValue(r.Elo1, true)
+	switch r.Verdict() {
+	case SPRTPassed:
+		fmt.Fprintf(&sb, "%v: H1 accepted, the candidate gains at least %s Elo\n", SPRTPassed, h1)
+	case SPRTFailed:
+		fmt.Fprintf(&sb, "%v: H0 accepted, the candidate gains at most %s Elo\n", SPRTFailed, h0)
+	default:
+		fmt.Fprintf(&sb, "%v: neither a gain of %s nor of %s Elo could be shown\n", SPRTUndecided, h0, h1)
+	}
+	fmt.Fprintf(&sb, "  %v, Elo %s\n", r.SPRTStatus, formatElo(r.Score.Elo()))
+	return sb.String()
+}
+
+// LLR is the log-likelihood ratio of the score coming from a side elo1 stronger than its
+// opponent against it coming from a side elo0 stronger. It uses the normal approximation of
+// the score with the spread of the results so far. A score with no spread, such as every game
+// won, counts a win and a loss on top so it still tells the hypotheses apart.
+func (s Score) LLR(elo0, elo1 float64) float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	counted := s
+	if counted.variance() == 0 {
+		counted = counted.Add(Score{Wins: 1, Losses: 1})
+	}
+	n, p, variance := float64(counted.Games()), counted.Fraction(), counted.variance()
+	s0, s1 := ExpectedScore(elo0), ExpectedScore(elo1)
+	return n * (s1 - s0) * (2*p - s0 - s1) / (2 * variance)
+}
+
+// Run plays games until the test passes or fails, MaxGames are played or ctx is cancelled.
+// A cancelled test returns the games counted so far and ctx.Err().
+func (t *SPRT) Run(ctx context.Context) (SPRTResult, error) {
+	alpha, beta := t.Alpha, t.Beta
+	if alpha == 0 {
+		alpha = defaultSPRTError
+	}
+	if beta == 0 {
+		beta = defaultSPRTError
+	}
+	switch {
+	case t.Candidate.NewEngine == nil || t.Baseline.NewEngine == nil:
+		return SPRTResult{}, fmt.Errorf("%w: the candidate and the baseline both need an engine", ErrInvalidTournament)
+	case t.Elo1 <= t.Elo0:
+		return SPRTResult{}, fmt.Errorf("%w: Elo1 %v isn't more than Elo0 %v", ErrInvalidTournament, t.Elo1, t.Elo0)
+	case alpha <= 0 || alpha >= 0.5 || beta <= 0 || beta >= 0.5:
+		return SPRTResult{}, fmt.Errorf("%w: error chances %v and %v aren't between 0 and 0.5", ErrInvalidTournament, alpha, beta)
+	}
+	board := t.Board
+	if board.numCols == 0 {
+		board = NewBoard()
+	}
+	if _, err := randomOpening(board, t.Openings, rand.New(rand.NewSource(t.Seed))); err != nil {
+		return SPRTResult{}, err
+	}
+
+	result := SPRTResult{
+		SPRTStatus: SPRTStatus{Lower: math.Log(beta / (1 - alpha)), Upper: math.Log((1 - beta) / alpha)},
+		Elo0:       t.Elo0,
+		Elo1:       t.Elo1,
+	}
+
+	// the candidate is entrant 0 and the baseline entrant 1, game pairs are scheduled until the test is over
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queue := make(chan gameJob)
+	go func() {
+		defer close(queue)
+		rng := rand.New(rand.NewSource(t.Seed))
+		for index, round := 0, 0; t.MaxGames == 0 || index < t.MaxGames; round++ {
+			opening, err := randomOpening(board, t.Openings, rng)
+			if err != nil {
+				return // as unlikely as it is after the first opening was found, the test ends undecided
+			}
+			for _, players := range [2][2]int{{0, 1}, {1, 0}} {
+				select {
+				case queue <- gameJob{index: index, round: round, players: players, opening: opening}:
+					index++
+				case <-stop.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// games are counted in the order they were scheduled, those ending early wait their turn
+	waiting := map[int]TournamentGame{}
+	workers := t.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	for g := range playGames(stop, []Entrant{t.Candidate, t.Baseline}, board, queue, workers) {
+		waiting[g.Index] = g
+		for {
+			next, ok := waiting[len(result.Games)]
+			if !ok || stop.Err() != nil {
+				break
+			}
+			delete(waiting, next.Index)
+
+			score := next.Score()
+			if next.Players[0] != 0 {
+				score = score.Reversed()
+			}
+			result.Score = result.Score.Add(score)
+			result.LLR = result.Score.LLR(t.Elo0, t.Elo1)
+			result.Games = append(result.Games, next)
+			if t.OnGame != nil {
+				t.OnGame(next, result.SPRTStatus)
+			}
+
+			if result.Verdict() != SPRTUndecided || len(result.Games) == t.MaxGames {
+				cancel() // the games still being played aren't counted
+			}
+		}
+	}
+	return result, ctx.Err()
+}
+
+// RunSPRT asks for a candidate and a baseline engine and the hypotheses and tests whether the
+// candidate gains Elo, printing the progress and the verdict. Entering q stops it undecided.
+func RunSPRT() {
+	fmt.Println("------------- Connect 4 engine SPRT -------------")
+	names := map[string]int{}
+	fmt.Println("Candidate engine:")
+	candidate, ok := promptEntrant(names)
+	if !ok {
+		return
+	}
+	fmt.Println("Baseline engine:")
+	baseline, ok := promptEntrant(names)
+	if !ok {
+		return
+	}
+
+	t := SPRT{Candidate: candidate, Baseline: baseline}
+	t.Elo0 = float64(promptCount("Elo gain of H0, the change doesn't help", -100, 100, 0))
+	t.Elo1 = float64(promptCount("Elo gain of H1, the change helps", int(t.Elo0)+1, 500, int(t.Elo0)+10))
+	t.MaxGames = promptCount("Games to give up after", 2, 100000, 5000)
+	t.Openings = uint(promptCount("Random moves to open each game pair", 0, 10, 2))
+	t.Seed = int64(promptCount("Seed for the openings", 0, math.MaxInt32, 1))
+	t.Workers = promptCount("Games to play at once", 1, 64, runtime.NumCPU())
+
+	t.OnGame = func(g TournamentGame, status SPRTStatus) {
+		if games := status.Score.Games(); games%10 == 0 || status.Verdict() != SPRTUndecided || games == t.MaxGames {
+			fmt.Println(status)
+		}
+	}
+
+	// q stops the test
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	readQuit(done, cancel)
+
+	fmt.Printf("\n%s against %s, H0 %s Elo against H1 %s Elo, q and Enter to stop\n",
+		candidate.Name, baseline.Name, formatEloValue(t.Elo0, true), formatEloValue(t.Elo1, true))
+	result, err := t.Run(ctx)
+	close(done)
+	if err != nil && ctx.Err() == nil {
+		fmt.Println("The test could not be run:", err)
+		return
+	}
+	fmt.Println("\n------------- SPRT verdict -------------")
+	fmt.Print(result)
+}
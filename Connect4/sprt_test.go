@@ -0,0 +1,97 @@
+package connect4
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScoreLLR(t *testing.T) {
+	if llr := (Score{}).LLR(0, 10); llr != 0 {
+		t.Errorf("LLR without games = %v", llr)
+	}
+	// a score right between the hypotheses favours neither
+	even := Score{Wins: 30, Draws: 40, Losses: 30}
+	if llr := even.LLR(-10, 10); llr > 1e-9 || llr < -1e-9 {
+		t.Errorf("%v.LLR(-10, 10) = %v, want 0", even, llr)
+	}
+	if even.LLR(0, 20) >= 0 || (Score{Wins: 50, Draws: 30, Losses: 20}).LLR(0, 20) <= 0 {
+		t.Error("LLR leans the wrong way")
+	}
+	// every game won still has a spread and favours H1
+	if llr := (Score{Wins: 10}).LLR(0, 50); llr <= 2.94 {
+		t.Errorf("LLR of 10 straight wins = %v, want a pass", llr)
+	}
+}
+
+func TestSPRTVerdicts(t *testing.T) {
+	minimax := Entrant{Name: "Minimax 2", NewEngine: func() Engine { return NewMiniMaxEngine(2) }}
+	random := Entrant{Name: "Random", NewEngine: func() Engine { return NewRandomEngine(3) }}
+
+	test := SPRT{Candidate: minimax, Baseline: random, Elo0: 0, Elo1: 50, Openings: 2, Seed: 5, Workers: 4}
+	var counted int
+	test.OnGame = func(g TournamentGame, status SPRTStatus) {
+		if g.Index != counted {
+			t.Errorf("game %d counted as game %d", g.Index, counted)
+		}
+		counted++
+	}
+	result, err := test.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Passed() || counted != len(result.Games) || result.Score.Games() != len(result.Games) {
+		t.Fatalf("minimax against random:\n%s", result)
+	}
+	if !strings.HasPrefix(result.String(), "PASS") {
+		t.Errorf("String() = %q", result)
+	}
+
+	test.Candidate, test.Baseline, test.OnGame = random, minimax, nil
+	if result, err = test.Run(context.Background()); err != nil || result.Verdict() != SPRTFailed {
+		t.Errorf("random against minimax: %v\n%s", err, result)
+	}
+
+	test.MaxGames = 3
+	test.Elo0, test.Elo1 = 1000, 1100
+	if result, err = test.Run(context.Background()); err != nil || result.Verdict() != SPRTUndecided || len(result.Games) != 3 {
+		t.Errorf("3 games against hypotheses far apart: %v\n%s", err, result)
+	}
+}
+
+func TestSPRTIsDeterministic(t *testing.T) {
+	test := SPRT{
+		Candidate: Entrant{Name: "Minimax 3", NewEngine: func() Engine { return NewMiniMaxEngine(3) }},
+		Baseline:  Entrant{Name: "Minimax 1", NewEngine: func() Engine { return NewMiniMaxEngine(1) }},
+		Elo0:      0, Elo1: 100, MaxGames: 16, Openings: 3, Seed: 11, Workers: 4,
+	}
+	first, err := test.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := test.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.SPRTStatus != second.SPRTStatus || len(first.Games) != len(second.Games) {
+		t.Fatalf("two runs with the same seed: %v and %v", first.SPRTStatus, second.SPRTStatus)
+	}
+	for i := range first.Games {
+		if !reflect.DeepEqual(first.Games[i].Opening, second.Games[i].Opening) ||
+			first.Games[i].Record.String() != second.Games[i].Record.String() {
+			t.Errorf("game %d differs:\n%s\n%s", i, first.Games[i].Record, second.Games[i].Record)
+		}
+	}
+
+	for _, bad := range []SPRT{
+		{Candidate: test.Candidate, Baseline: test.Baseline, Elo0: 10, Elo1: 10},
+		{Candidate: test.Candidate, Elo1: 10},
+		{Candidate: test.Candidate, Baseline: test.Baseline, Elo1: 10, Alpha: 0.6},
+	} {
+		if _, err := bad.Run(context.Background()); !errors.Is(err, ErrInvalidTournament) {
+			t.Errorf("Run of %+v: error = %v, want ErrInvalidTournament", bad, err)
+		}
+	}
+}
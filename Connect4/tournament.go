@@ -0,0 +1,479 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------------
+// Tournaments - engines playing each other for a score
+// ------------------------------------------------------
+// A Tournament plays engine configurations against each other, either every one against every
+// other (round-robin) or the first against all the rest (gauntlet), to find out whether a change
+// to Evaluate or the search makes an engine stronger.
+// Games are played in pairs from the same opening with each entrant moving first once, so neither
+// gets the better of the openings or of moving first. The openings are random moves picked with
+// a seed, so a tournament can be played again on the same openings.
+// Games are played at the same time on a pool of workers, every game with engines of its own.
+
+// TournamentFormat is how the entrants of a tournament are paired up
+type TournamentFormat int
+
+const (
+	RoundRobin TournamentFormat = iota // every entrant plays every other
+	Gauntlet                           // the first entrant plays every other, the others don't play each other
+)
+
+func (f TournamentFormat) String() string {
+	switch f {
+	case RoundRobin:
+		return "Round-robin"
+	case Gauntlet:
+		return "Gauntlet"
+	}
+	return fmt.Sprintf("TournamentFormat(%d)", int(f))
+}
+
+// Entrant is an engine configuration taking part in a tournament
+type Entrant struct {
+	Name string
+
+	// NewEngine returns the engine for one game, engines aren't shared between games
+	// as they keep state such as their transposition table and games are played at once
+	NewEngine func() Engine
+}
+
+// Tournament is a set of games between the entrants
+type Tournament struct {
+	Entrants []Entrant
+	Format   TournamentFormat
+	Rounds   int     // game pairs between each pairing of entrants
+	Openings uint    // random moves played before the engines take over, 0 to start from Board
+	Seed     int64   // picks the openings
+	Workers  int     // games played at once, 0 for one per CPU
+	Board    C4Board // the board every game starts on, the zero board for NewBoard
+
+	// OnGame is called as each game ends, from one goroutine at a time but not in the order of the schedule
+	OnGame func(g TournamentGame)
+}
+
+// TournamentGame is one game of a tournament
+type TournamentGame struct {
+	Index   int    // place in the schedule
+	Round   int    // from 0
+	Players [2]int // the entrants, first mover first
+	Opening []Move
+	Summary MatchSummary
+	Record  GameRecord
+}
+
+// Score is how the game went for the first mover.
+// An engine that didn't play a legal move loses the game.
+func (g TournamentGame) Score() Score {
+	result := g.Summary.Result
+	switch {
+	case result.Outcome == Draw:
+		return Score{Draws: 1}
+	case result.Outcome == Win && result.Winner == g.Summary.Sides[0].Player.Piece:
+		return Score{Wins: 1}
+	case result.Outcome == Win:
+		return Score{Losses: 1}
+	case g.Summary.Moves%2 == 0:
+		return Score{Losses: 1} // the first mover was to move
+	}
+	return Score{Wins: 1}
+}
+
+// TournamentResult is every game played in a tournament and the crosstable of their scores
+type TournamentResult struct {
+	Games      []TournamentGame // in the order of the schedule
+	Crosstable Crosstable
+}
+
+// Crosstable is the scores of the entrants of a tournament against each other
+type Crosstable struct {
+	Entrants []Entrant
+	Scores   [][]Score // Scores[i][j] is entrant i's score against entrant j
+}
+
+// NewCrosstable returns a crosstable of the games between entrants
+func NewCrosstable(entrants []Entrant, games []TournamentGame) Crosstable {
+	c := Crosstable{Entrants: entrants, Scores: make([][]Score, len(entrants))}
+	for i := range c.Scores {
+		c.Scores[i] = make([]Score, len(entrants))
+	}
+	for _, g := range games {
+		first, second := g.Players[0], g.Players[1]
+		score := g.Score()
+		c.Scores[first][second] = c.Scores[first][second].Add(score)
+		c.Scores[second][first] = c.Scores[second][first].Add(score.Reversed())
+	}
+	return c
+}
+
+// Total is entrant i's score against every opponent
+func (c Crosstable) Total(i int) Score {
+	var total Score
+	for _, score := range c.Scores[i] {
+		total = total.Add(score)
+	}
+	return total
+}
+
+// String writes the crosstable with a row for each entrant: its total, its Elo against the
+// opponents it played and its wins-draws-losses against each other entrant
+func (c Crosstable) String() string {
+	width := len("Engine")
+	for _, e := range c.Entrants {
+		width = max(width, len(e.Name))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "    %-*s %5s %6s  %-16s", width, "Engine", "Games", "Score", "Elo")
+	for j := range c.Entrants {
+		fmt.Fprintf(&sb, " %9d", j+1)
+	}
+	sb.WriteString("\n")
+
+	for i, e := range c.Entrants {
+		total := c.Total(i)
+		fmt.Fprintf(&sb, "%2d. %-*s %5d %5.1f%%  %-16s", i+1, width, e.Name, total.Games(), 100*total.Fraction(), formatElo(total.Elo()))
+		for j := range c.Entrants {
+			switch score := c.Scores[i][j]; {
+			case i == j:
+				fmt.Fprintf(&sb, " %9s", "-")
+			case score.Games() == 0:
+				fmt.Fprintf(&sb, " %9s", "")
+			default:
+				fmt.Fprintf(&sb, " %9s", score)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// gameJob is a game waiting to be played by playGames
+type gameJob struct {
+	index   int
+	round   int
+	players [2]int
+	opening []Move
+}
+
+// Run plays the tournament and returns the games and the crosstable.
+// Cancelling ctx stops it starting new games, it returns the games that were finished and ctx.Err().
+func (t *Tournament) Run(ctx context.Context) (TournamentResult, error) {
+	jobs, err := t.schedule()
+	if err != nil {
+		return TournamentResult{}, err
+	}
+
+	queue := make(chan gameJob)
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	played := make([]*TournamentGame, len(jobs))
+	for g := range playGames(ctx, t.Entrants, t.board(), queue, t.Workers) {
+		if t.OnGame != nil {
+			t.OnGame(g)
+		}
+		played[g.Index] = &g
+	}
+
+	var result TournamentResult
+	for _, g := range played {
+		if g != nil {
+			result.Games = append(result.Games, *g)
+		}
+	}
+	result.Crosstable = NewCrosstable(t.Entrants, result.Games)
+	return result, ctx.Err()
+}
+
+// board is the board every game starts on
+func (t *Tournament) board() C4Board {
+	if t.Board.numCols == 0 {
+		return NewBoard()
+	}
+	return t.Board
+}
+
+// pairings returns the pairs of entrants that play each other
+func (t *Tournament) pairings() [][2]int {
+	var pairs [][2]int
+	for i := range t.Entrants {
+		for j := i + 1; j < len(t.Entrants); j++ {
+			if t.Format == Gauntlet && i > 0 {
+				break
+			}
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	return pairs
+}
+
+// schedule returns every game of the tournament a round at a time, so a tournament
+// stopped early has played about as many games between every pairing
+func (t *Tournament) schedule() ([]gameJob, error) {
+	switch {
+	case len(t.Entrants) < 2:
+		return nil, fmt.Errorf("%w: %d entrants, it takes two", ErrInvalidTournament, len(t.Entrants))
+	case t.Rounds < 1:
+		return nil, fmt.Errorf("%w: %d rounds", ErrInvalidTournament, t.Rounds)
+	case t.Format != RoundRobin && t.Format != Gauntlet:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTournament, t.Format)
+	}
+	for _, e := range t.Entrants {
+		if e.NewEngine == nil {
+			return nil, fmt.Errorf("%w: %q has no engine", ErrInvalidTournament, e.Name)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(t.Seed))
+	var jobs []gameJob
+	for round := 0; round < t.Rounds; round++ {
+		for _, pair := range t.pairings() {
+			opening, err := randomOpening(t.board(), t.Openings, rng)
+			if err != nil {
+				return nil, err
+			}
+			for _, players := range [2][2]int{pair, {pair[1], pair[0]}} {
+				jobs = append(jobs, gameJob{index: len(jobs), round: round, players: players, opening: opening})
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// How many times randomOpening tries for an opening that doesn't end the game
+const openingTries = 100
+
+// randomOpening returns length random moves from start that don't end the game
+func randomOpening(start C4Board, length uint, rng *rand.Rand) ([]Move, error) {
+	for try := 0; try < openingTries; try++ {
+		board := start
+		var opening []Move
+		for uint(len(opening)) < length && !board.IsGameOver() {
+			moves := board.LegalMoves()
+			move := moves[rng.Intn(len(moves))]
+			board = board.MakeMove(Player{Piece: board.nextPiece()}, move)
+			opening = append(opening, move)
+		}
+		if !board.IsGameOver() {
+			return opening, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: openings of %d random moves keep ending the game", ErrInvalidTournament, length)
+}
+
+// playGames plays the jobs on workers goroutines, 0 for one per CPU, and sends each game as it ends.
+// Once ctx is cancelled no more games are started. The channel is closed when the last game ends.
+func playGames(ctx context.Context, entrants []Entrant, start C4Board, jobs <-chan gameJob, workers int) <-chan TournamentGame {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	games := make(chan TournamentGame)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue // let the queue drain
+				}
+				games <- playGame(entrants, start, job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(games)
+	}()
+	return games
+}
+
+// playGame plays out one game of a tournament, the opening moves are made before the engines take over
+func playGame(entrants []Entrant, start C4Board, job gameJob) TournamentGame {
+	board := start
+	for _, move := range job.opening {
+		board = board.MakeMove(Player{Piece: board.nextPiece()}, move)
+	}
+
+	var players [2]Player
+	piece := board.nextPiece()
+	for seat, e := range job.players {
+		players[seat] = Player{Name: entrants[e].Name, Piece: piece, Engine: entrants[e].NewEngine()}
+		piece = piece.opposite()
+	}
+
+	session := NewSessionOn(board, players[0], players[1])
+	recorder := NewGameRecorder(session)
+	session.AddListener(recorder)
+	summary := (&Spectator{Session: session}).Run()
+
+	record := recorder.Record()
+	record.SetTag("Event", "Tournament")
+	record.SetTag("Round", fmt.Sprint(job.round+1))
+	if len(job.opening) > 0 {
+		record.SetTag("Opening", FormatMoves(job.opening))
+	}
+	return TournamentGame{
+		Index:   job.index,
+		Round:   job.round,
+		Players: job.players,
+		Opening: job.opening,
+		Summary: summary,
+		Record:  record,
+	}
+}
+
+// The file tournament games are added to as game records, in the directory the program is run from
+const tournamentFile = "connect4-tournament.txt"
+
+// The most engines a tournament can be set up with from the console
+const maxEntrants = 8
+
+// PlayTournament asks for the engines and how the tournament is played, plays it showing each
+// result as it comes in and prints the crosstable. Entering q stops it after the games being played.
+func PlayTournament() {
+	fmt.Println("------------- Connect 4 engine tournament -------------")
+
+	entrants := make([]Entrant, promptCount("How many engines", 2, maxEntrants, 2))
+	names := map[string]int{}
+	for i := range entrants {
+		fmt.Printf("Engine %d:\n", i+1)
+		var ok bool
+		if entrants[i], ok = promptEntrant(names); !ok {
+			return
+		}
+	}
+
+	t := Tournament{Entrants: entrants, Seed: time.Now().UnixNano()}
+	if len(entrants) > 2 && promptYesNo("Play a gauntlet of the first engine against the others (n for round-robin)?") {
+		t.Format = Gauntlet
+	}
+	t.Rounds = promptCount("Game pairs between each two engines", 1, 1000, 10)
+	t.Openings = uint(promptCount("Random moves to open each game pair", 0, 10, 2))
+	t.Workers = promptCount("Games to play at once", 1, 64, runtime.NumCPU())
+	t.Board = promptBoard()
+
+	games := len(t.pairings()) * t.Rounds * 2
+	played := 0
+	t.OnGame = func(g TournamentGame) {
+		played++
+		fmt.Printf("Game %d/%d: %s vs %s %s after %d moves\n", played, games,
+			entrants[g.Players[0]].Name, entrants[g.Players[1]].Name, g.Record.Result(), g.Summary.Moves)
+	}
+
+	// q stops the tournament
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	readQuit(done, func() {
+		fmt.Println("Stopping once the games being played are over.")
+		cancel()
+	})
+
+	fmt.Printf("\n%s of %d games, q and Enter to stop\n", t.Format, games)
+	result, err := t.Run(ctx)
+	close(done)
+	if err != nil && ctx.Err() == nil {
+		fmt.Println("The tournament could not be played:", err)
+		return
+	}
+
+	fmt.Println("\n------------- Crosstable -------------")
+	fmt.Print(result.Crosstable)
+	for _, g := range result.Games {
+		if err := AppendRecord(tournamentFile, g.Record); err != nil {
+			fmt.Println("The games could not be recorded:", err)
+			return
+		}
+	}
+	fmt.Printf("The games were added to %s\n", tournamentFile)
+}
+
+// readQuit calls quit from a goroutine of its own if q is typed in before done is closed.
+// It stops reading at the end of the input, after the q or once done is closed,
+// so the lines typed in after that are left for the next prompt.
+func readQuit(done <-chan struct{}, quit func()) {
+	go func() {
+		lines := inputLines()
+		for {
+			// a line read after done was closed is left for the next prompt
+			select {
+			case <-done:
+				return
+			default:
+			}
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				if strings.EqualFold(strings.TrimSpace(line), "q") {
+					quit()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// promptEntrant asks for an engine from the opponents menu and its depth. names counts the names
+// given so far, the same engine entered twice, such as to measure how much a random one varies,
+// is told apart by a number. Returns false once there is nothing left to read.
+func promptEntrant(names map[string]int) (Entrant, bool) {
+	choice, ok := promptOpponent()
+	if !ok {
+		return Entrant{}, false
+	}
+	opponent := opponents[choice]
+	newEngine := opponent.NewEngine
+	if opponent.WithDepth != nil {
+		depth := promptDepth(opponent.DefaultDepth)
+		newEngine = func() Engine { return opponent.WithDepth(depth) }
+	}
+
+	name := newEngine().Name()
+	if names[name]++; names[name] > 1 {
+		name = fmt.Sprintf("%s #%d", name, names[name])
+	}
+	return Entrant{Name: name, NewEngine: newEngine}, true
+}
+
+// promptCount asks question for a number from low to high, empty answers and the end of the input are defaultCount
+func promptCount(question string, low, high, defaultCount int) int {
+	for {
+		fmt.Printf("%s, from %d to %d (Enter for %d): ", question, low, high, defaultCount)
+
+		answer, _ := readLine()
+		if answer == "" {
+			return defaultCount
+		}
+		var count int
+		if _, err := fmt.Sscanf(answer, "%d", &count); err != nil || count < low || count > high {
+			fmt.Printf("Please enter a number from %d to %d.\n", low, high)
+			continue
+		}
+		return count
+	}
+}
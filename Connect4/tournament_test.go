@@ -0,0 +1,134 @@
+package connect4
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testEntrants() []Entrant {
+	return []Entrant{
+		{Name: "Minimax 2", NewEngine: func() Engine { return NewMiniMaxEngine(2) }},
+		{Name: "Greedy", NewEngine: func() Engine { return GreedyEngine{} }},
+		{Name: "Random", NewEngine: func() Engine { return NewRandomEngine(1) }},
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	tour := Tournament{Entrants: testEntrants(), Rounds: 2, Openings: 2, Seed: 7, Workers: 4}
+	var seen int
+	tour.OnGame = func(g TournamentGame) { seen++ }
+	result, err := tour.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// three pairings, two rounds of a game pair each
+	if len(result.Games) != 12 || seen != 12 {
+		t.Fatalf("%d games played, %d reported, want 12", len(result.Games), seen)
+	}
+	for i, g := range result.Games {
+		if g.Index != i || len(g.Opening) != 2 || g.Summary.Stopped || g.Summary.Result.Outcome == InProgress {
+			t.Errorf("game %d: %+v", i, g)
+		}
+		if g.Record.Tag("Opening") != FormatMoves(g.Opening) || g.Record.Tag("First") != tour.Entrants[g.Players[0]].Name {
+			t.Errorf("game %d record tags %v", i, g.Record.Tags)
+		}
+	}
+	// each game pair plays the same opening with the entrants swapped
+	for i := 0; i < len(result.Games); i += 2 {
+		a, b := result.Games[i], result.Games[i+1]
+		if !reflect.DeepEqual(a.Opening, b.Opening) || a.Players != [2]int{b.Players[1], b.Players[0]} {
+			t.Errorf("games %d and %d aren't a pair: %v %v, %v %v", i, i+1, a.Players, a.Opening, b.Players, b.Opening)
+		}
+	}
+
+	c := result.Crosstable
+	for i := range c.Entrants {
+		if c.Total(i).Games() != 8 {
+			t.Errorf("%s played %d games, want 8", c.Entrants[i].Name, c.Total(i).Games())
+		}
+		for j := range c.Entrants {
+			if c.Scores[i][j] != c.Scores[j][i].Reversed() {
+				t.Errorf("scores %d v %d don't match: %v and %v", i, j, c.Scores[i][j], c.Scores[j][i])
+			}
+		}
+	}
+	if c.Total(0).Fraction() <= c.Total(2).Fraction() {
+		t.Errorf("minimax scored no better than random:\n%s", c)
+	}
+	if lines := strings.Split(strings.TrimSpace(c.String()), "\n"); len(lines) != 4 || !strings.Contains(lines[1], "Minimax 2") {
+		t.Errorf("crosstable\n%s", c)
+	}
+
+	// the same seed gives the same openings
+	again, err := tour.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again.Games {
+		if !reflect.DeepEqual(again.Games[i].Opening, result.Games[i].Opening) {
+			t.Errorf("game %d opened %v, then %v", i, result.Games[i].Opening, again.Games[i].Opening)
+		}
+	}
+}
+
+func TestGauntletAndStopping(t *testing.T) {
+	tour := Tournament{Entrants: testEntrants(), Format: Gauntlet, Rounds: 1, Workers: 1}
+	result, err := tour.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Games) != 4 || result.Crosstable.Scores[1][2].Games() != 0 || result.Crosstable.Total(0).Games() != 4 {
+		t.Errorf("gauntlet played %d games:\n%s", len(result.Games), result.Crosstable)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tour.OnGame = func(TournamentGame) { cancel() }
+	result, err = tour.Run(ctx)
+	if !errors.Is(err, context.Canceled) || len(result.Games) == 0 || len(result.Games) == 4 {
+		t.Errorf("stopped tournament played %d games, error %v", len(result.Games), err)
+	}
+
+	for _, bad := range []Tournament{
+		{Entrants: testEntrants()[:1], Rounds: 1},
+		{Entrants: testEntrants(), Rounds: 0},
+		{Entrants: []Entrant{{Name: "None"}, {Name: "Nothing"}}, Rounds: 1},
+		{Entrants: testEntrants(), Rounds: 1, Openings: 42},
+	} {
+		if _, err := bad.Run(context.Background()); !errors.Is(err, ErrInvalidTournament) {
+			t.Errorf("Run of %+v: error = %v, want ErrInvalidTournament", bad, err)
+		}
+	}
+}
+
+func TestReadQuit(t *testing.T) {
+	withStdin(t, "x\n q \nnext\n")
+	quit := make(chan struct{})
+	readQuit(make(chan struct{}), func() { close(quit) })
+	<-quit
+	if line, ok := readLine(); line != "next" || !ok {
+		t.Errorf("readLine() = %q, %v after the q, want next", line, ok)
+	}
+	if count := promptCount("Games", 1, 9, 4); count != 4 {
+		t.Errorf("promptCount() at the end of the input = %d, want 4", count)
+	}
+}
+
+func TestReadQuitDone(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	withStdinReader(t, r)
+
+	// once the tournament is over the reader stops and the next answer goes to the prompt asking for it
+	done := make(chan struct{})
+	readQuit(done, func() { t.Error("quit without a q") })
+	close(done)
+	go io.WriteString(w, "q\n")
+	if line, ok := readLine(); line != "q" || !ok {
+		t.Errorf("readLine() = %q, %v after the tournament, want q", line, ok)
+	}
+}
@@ -0,0 +1,133 @@
+package connect4
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Bound tells how a stored score relates to the real minimax score of the position
+type Bound uint8
+
+const (
+	ExactBound Bound = iota // the score is the minimax score
+	LowerBound              // the search failed high, the real score is at least the stored score
+	UpperBound              // the search failed low, the real score is at most the stored score
+)
+
+// Number of entries in the table created by FindBestMove and ConcurrentFindBestMove
+const DefaultTableSize = 1 << 18
+
+// TTEntry is a single stored search result
+type TTEntry struct {
+	Key   uint64
+	Depth uint
+	Score float32
+	Bound Bound
+	Move  Move // the best move found in the position
+	used  bool
+}
+
+// TranspositionTable remembers search results by position so a position reached through
+// a different move order isn't searched again.
+// It has a fixed number of slots, a new entry for a slot replaces the old one unless the old one is
+// for the same position and was searched deeper.
+// It is safe to share between goroutines.
+type TranspositionTable struct {
+	mu      sync.Mutex
+	entries []TTEntry
+	mask    uint64
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// NewTranspositionTable returns a table with room for size entries.
+// size is rounded up to the next power of two (minimum 1).
+func NewTranspositionTable(size int) *TranspositionTable {
+	slots := 1
+	for slots < size {
+		slots <<= 1
+	}
+	return &TranspositionTable{
+		entries: make([]TTEntry, slots),
+		mask:    uint64(slots - 1),
+	}
+}
+
+// Probe looks up key and returns the entry if it was searched at least depth deep.
+// Every call counts as a hit or a miss.
+// A nil table never has anything in it.
+func (tt *TranspositionTable) Probe(key uint64, depth uint) (TTEntry, bool) {
+	return tt.probe(key, depth, false)
+}
+
+// probe is Probe that with sameDepth only returns an entry searched exactly depth deep.
+// A deeper score can differ from the one a search of depth would find,
+// the fixed depth searches need the same score to pick the move MiniMax would.
+func (tt *TranspositionTable) probe(key uint64, depth uint, sameDepth bool) (TTEntry, bool) {
+	if tt == nil {
+		return TTEntry{}, false
+	}
+
+	tt.mu.Lock()
+	entry := tt.entries[key&tt.mask]
+	tt.mu.Unlock()
+
+	if entry.used && entry.Key == key && (entry.Depth == depth || !sameDepth && entry.Depth > depth) {
+		tt.hits.Add(1)
+		return entry, true
+	}
+	tt.misses.Add(1)
+	return TTEntry{}, false
+}
+
+// lookup returns the entry for key whatever its depth, without counting a hit or miss
+func (tt *TranspositionTable) lookup(key uint64) (TTEntry, bool) {
+	if tt == nil {
+		return TTEntry{}, false
+	}
+
+	tt.mu.Lock()
+	entry := tt.entries[key&tt.mask]
+	tt.mu.Unlock()
+
+	return entry, entry.used && entry.Key == key
+}
+
+// Store saves a search result for key
+func (tt *TranspositionTable) Store(key uint64, depth uint, score float32, bound Bound, move Move) {
+	if tt == nil {
+		return
+	}
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	slot := &tt.entries[key&tt.mask]
+	// Keep the deeper result for the same position, otherwise the newest entry wins
+	if slot.used && slot.Key == key && slot.Depth > depth {
+		return
+	}
+	*slot = TTEntry{Key: key, Depth: depth, Score: score, Bound: bound, Move: move, used: true}
+}
+
+// Stats returns how many probes found a usable entry and how many didn't
+func (tt *TranspositionTable) Stats() (hits, misses uint64) {
+	if tt == nil {
+		return 0, 0
+	}
+	return tt.hits.Load(), tt.misses.Load()
+}
+
+// Clear empties the table and resets the counters
+func (tt *TranspositionTable) Clear() {
+	if tt == nil {
+		return
+	}
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	clear(tt.entries)
+	tt.hits.Store(0)
+	tt.misses.Store(0)
+}
@@ -0,0 +1,126 @@
+package connect4
+
+import "testing"
+
+// scratchHash hashes every piece on b from scratch
+func scratchHash(b C4Board) uint64 {
+	var hash uint64
+	for col := range b.position {
+		for row, piece := range b.position[col] {
+			if piece != Empty {
+				hash ^= zobristPieces[col][row][piece]
+			}
+		}
+	}
+	return hash
+}
+
+func TestHashIsIncremental(t *testing.T) {
+	moves := "2364171236641561122122677643745"
+	b, p := NewBoard(), Player{Piece: PlayerIcon}
+	for i, c := range moves {
+		b = b.MakeMove(p, Move(c-'1'))
+		p = p.Opponent()
+		if b.Hash() != scratchHash(b) {
+			t.Errorf("move %d: hash %x after the moves, %x from scratch", i+1, b.Hash(), scratchHash(b))
+		}
+	}
+	if b.Hash() == NewBoard().Hash() {
+		t.Error("same hash as the empty board")
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		same bool
+	}{
+		{"1234", "3214", true},
+		{"4455", "5544", true},
+		{"443322", "224433", true},
+		{"12", "21", false},
+		{"44", "4", false},
+	} {
+		a, _ := playMoves(t, tc.a)
+		b, _ := playMoves(t, tc.b)
+		if same := a.Hash() == b.Hash(); same != tc.same {
+			t.Errorf("%q and %q: same hash %v, want %v", tc.a, tc.b, same, tc.same)
+		}
+	}
+}
+
+func TestTranspositionTableStats(t *testing.T) {
+	tt := NewTranspositionTable(16)
+	tt.Store(42, 3, 1.5, ExactBound, 2)
+
+	if _, ok := tt.Probe(42, 4); ok {
+		t.Error("an entry searched 3 deep was used for depth 4")
+	}
+	if entry, ok := tt.Probe(42, 2); !ok || entry.Score != 1.5 || entry.Move != 2 {
+		t.Errorf("Probe(42, 2) = %+v, %v", entry, ok)
+	}
+	if _, ok := tt.Probe(43, 1); ok {
+		t.Error("found an entry that was never stored")
+	}
+	if hits, misses := tt.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Stats() = %d hits %d misses, want 1 and 2", hits, misses)
+	}
+
+	tt.Clear()
+	b, p := playMoves(t, "4453")
+	FindBestMoveTT(b, p, 5, tt)
+	if hits, misses := tt.Stats(); hits == 0 || misses == 0 {
+		t.Errorf("a depth 5 search made %d hits and %d misses", hits, misses)
+	}
+}
+
+// Tables kept from one search to the next hold deeper results than the next search asks for,
+// the fixed depth searches must still pick the move MiniMax does
+func TestReusedTableMatchesMiniMax(t *testing.T) {
+	for _, tc := range searchPositions {
+		b, p := playMoves(t, tc.moves)
+		want, _ := miniMaxRoot(b, p, tc.depth-2)
+
+		tt := NewTranspositionTable(DefaultTableSize)
+		FindBestMoveTT(b, p, tc.depth+1, tt)
+		if got := FindBestMoveTT(b, p, tc.depth-2, tt); got != want {
+			t.Errorf("%q: FindBestMoveTT = %v after a deeper search, want MiniMax's %v", tc.moves, got, want)
+		}
+		if got := ConcurrentFindBestMoveTT(b, p, tc.depth-2, tt); got != want {
+			t.Errorf("%q: ConcurrentFindBestMoveTT = %v after a deeper search, want MiniMax's %v", tc.moves, got, want)
+		}
+	}
+}
+
+func TestSearchKeyVariants(t *testing.T) {
+	same := func(b C4Board) C4Board {
+		return b.MakeMove(Player{Piece: PlayerIcon}, 3).MakeMove(Player{Piece: CpuIcon}, 3)
+	}
+	boards := map[string]C4Board{"7x6": same(NewBoard())}
+	for name, newBoard := range map[string]func() (C4Board, error){
+		"8x7":      func() (C4Board, error) { return NewBoardSize(8, 7) },
+		"7x7":      func() (C4Board, error) { return NewBoardSize(7, 7) },
+		"connect5": func() (C4Board, error) { return NewConnectNBoard(NumCols, NumRows, 5) },
+		"PopOut":   func() (C4Board, error) { return NewPopOutBoard(NumCols, NumRows, WinLength) },
+	} {
+		b, err := newBoard()
+		if err != nil {
+			t.Fatal(err)
+		}
+		boards[name] = same(b)
+	}
+	// the same position again in PopOut after a drop and a pop by each player
+	boards["PopOut repeated"] = playPopOut(t, 3, 3, 0, 1, PopMove(0), PopMove(1))
+
+	keys := make(map[uint64]string)
+	for name, b := range boards {
+		if b.Hash() != boards["7x6"].Hash() {
+			t.Errorf("%s: the same pieces hash differently", name)
+		}
+		key := b.searchKey(PlayerIcon, PlayerIcon)
+		if other, ok := keys[key]; ok {
+			t.Errorf("%s and %s have the same search key", name, other)
+		}
+		keys[key] = name
+	}
+}
@@ -0,0 +1,77 @@
+package connect4
+
+import (
+	"math/rand"
+)
+
+// Zobrist keys used to hash a board position.
+// Every (column, row, piece) combination gets its own random 64 bit key and the hash of a board
+// is the XOR of the keys of every piece on it, so dropping a piece only costs a single XOR.
+// The keys come from a fixed seed so hashes are the same from one run to the next.
+var zobristPieces [MaxCols][MaxRows][3]uint64
+
+// Keys mixed into the board hash by the search for who is to move and whose point of view the score is from
+var zobristMover [3]uint64
+var zobristPerspective [3]uint64
+
+// Keys mixed into the search key for the rules of the game, so a table shared between games of
+// different variants never hands one of them a score from the other: the board size, the win
+// length, PopOut and, in PopOut, how many times the position has already been played
+var zobristCols [MaxCols + 1]uint64
+var zobristRows [MaxRows + 1]uint64
+var zobristWinLength [max(MaxCols, MaxRows) + 1]uint64
+var zobristPopOut uint64
+var zobristRepeats [popOutRepetitions]uint64
+
+const zobristSeed = 0x4334 // "C4"
+
+func init() {
+	r := rand.New(rand.NewSource(zobristSeed))
+	for col := range zobristPieces {
+		for row := range zobristPieces[col] {
+			for _, piece := range []Piece{PlayerIcon, CpuIcon} {
+				zobristPieces[col][row][piece] = r.Uint64()
+			}
+		}
+	}
+	for _, piece := range []Piece{PlayerIcon, CpuIcon} {
+		zobristMover[piece] = r.Uint64()
+		zobristPerspective[piece] = r.Uint64()
+	}
+	for i := range zobristCols {
+		zobristCols[i] = r.Uint64()
+	}
+	for i := range zobristRows {
+		zobristRows[i] = r.Uint64()
+	}
+	for i := range zobristWinLength {
+		zobristWinLength[i] = r.Uint64()
+	}
+	zobristPopOut = r.Uint64()
+	for i := range zobristRepeats {
+		zobristRepeats[i] = r.Uint64()
+	}
+}
+
+// Hash returns the Zobrist hash of the pieces on the board.
+// Two boards with the same pieces in the same cells have the same hash no matter
+// which order the moves were played in. The hash does not include whose turn it is.
+func (board C4Board) Hash() uint64 {
+	return board.hash
+}
+
+// searchKey is the key the search uses for the transposition table.
+// The same pieces with a different player to move, scored for a different player,
+// or on a board with different rules are different entries.
+func (board C4Board) searchKey(mover Piece, perspective Piece) uint64 {
+	return board.hash ^ zobristMover[mover] ^ zobristPerspective[perspective] ^ board.variantKey()
+}
+
+// variantKey is the part of the search key for the rules of the game, see zobristCols
+func (board C4Board) variantKey() uint64 {
+	key := zobristCols[board.numCols] ^ zobristRows[board.numRows] ^ zobristWinLength[board.winLength]
+	if board.popOut {
+		key ^= zobristPopOut ^ zobristRepeats[min(board.repeats(), popOutRepetitions-1)]
+	}
+	return key
+}